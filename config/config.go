@@ -28,10 +28,45 @@ type FileStore struct {
 	ClientId    string
 	Secret      string
 	RedirectUri string
+	Scopes      []string
+	LoginHint   string `toml:"login-hint"`
+	Tenant      string
+	AuthUrl     string `toml:"auth-url"`
+	TokenUrl    string `toml:"token-url"`
+	// google drive: export mime type by google document mime type, e.g.
+	// export = { "application/vnd.google-apps.document" = "application/pdf" }.
+	// Documents are exported to EPUB (application/epub+zip) and other google files to PDF if not set
+	Export map[string]string
+	// google drive: download files flagged as malware/spam
+	AcknowledgeAbuse bool `toml:"acknowledge-abuse"`
+	// box: server authentication of enterprise app ("ccg" or "jwt"), browser if not set
+	AuthMode    string `toml:"auth-mode"`
+	SubjectType string `toml:"subject-type"`
+	SubjectId   string `toml:"subject-id"`
+	KeyId       string `toml:"key-id"`
+	PrivateKey  string `toml:"private-key"`
+	Accounts    map[string]FileStore
+}
+
+// style of terminal UI: colors are names (default, black, red, green, yellow, blue, magenta, cyan, white)
+// or numbers of 256 colors palette, attributes are bold, dim, underline, reverse, italic or blink
+type StyleConfig struct {
+	Fg   string
+	Bg   string
+	Attr []string
+}
+
+type ThemeConfig struct {
+	// "dark", "light" or "mono"
+	Name string
+	// theme file with name and styles; styles of configuration file are applied after
+	File   string
+	Styles map[string]StyleConfig
 }
 
 type Config struct {
 	Database utils.Database
+	Http     utils.HttpConfig
 	Dirs     struct {
 		Workdir  string
 		Download string
@@ -45,11 +80,87 @@ type Config struct {
 		OneDrive FileStore
 		Box      FileStore
 	}
+	// metadata provider of enrich command; Open Library if not set
+	Enrich struct {
+		Provider string
+		Url      string
+	}
+	// key bindings of terminal screens: preset = "vi" or "emacs", and action = "key" or ["key", ...]
+	Keys       map[string]interface{}
+	Theme      ThemeConfig
 	ConfigFile string
 }
 
 var GetConfig func() Config
 
+// returns settings of a named account: fields not set in account are taken from vendor settings
+func (store FileStore) Account(name string) FileStore {
+	account := store
+	account.Accounts = nil
+
+	acc, ok := store.Accounts[name]
+	if len(name) == 0 || !ok {
+		return account
+	}
+
+	if len(acc.ClientId) > 0 {
+		account.ClientId = acc.ClientId
+	}
+	if len(acc.Secret) > 0 {
+		account.Secret = acc.Secret
+	}
+	if len(acc.RedirectUri) > 0 {
+		account.RedirectUri = acc.RedirectUri
+	}
+	if len(acc.Scopes) > 0 {
+		account.Scopes = acc.Scopes
+	}
+	if len(acc.LoginHint) > 0 {
+		account.LoginHint = acc.LoginHint
+	}
+	if len(acc.Tenant) > 0 {
+		account.Tenant = acc.Tenant
+	}
+	if len(acc.AuthUrl) > 0 {
+		account.AuthUrl = acc.AuthUrl
+	}
+	if len(acc.TokenUrl) > 0 {
+		account.TokenUrl = acc.TokenUrl
+	}
+	if len(acc.Export) > 0 {
+		account.Export = acc.Export
+	}
+	if acc.AcknowledgeAbuse {
+		account.AcknowledgeAbuse = true
+	}
+	if len(acc.AuthMode) > 0 {
+		account.AuthMode = acc.AuthMode
+	}
+	if len(acc.SubjectType) > 0 {
+		account.SubjectType = acc.SubjectType
+	}
+	if len(acc.SubjectId) > 0 {
+		account.SubjectId = acc.SubjectId
+	}
+	if len(acc.KeyId) > 0 {
+		account.KeyId = acc.KeyId
+	}
+	if len(acc.PrivateKey) > 0 {
+		account.PrivateKey = acc.PrivateKey
+	}
+	return account
+}
+
+// theme file in toml format: name = "..." and [styles.<style>] tables
+func LoadThemeFile(file string) (ThemeConfig, error) {
+	var theme ThemeConfig
+	_, err := toml.DecodeFile(ExpandPath(file), &theme)
+	if err != nil {
+		return theme, fmt.Errorf("Cannot read theme file %s: %v", file, err)
+	}
+	return theme, nil
+}
+
 func LoadConfig() error {
 
 	var conf Config
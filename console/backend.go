@@ -0,0 +1,161 @@
+package console
+
+import (
+	"github.com/rthornton128/goncurses"
+)
+
+// drawing and input of Terminal: curses on a real terminal, VirtualScreen in tests
+type Backend interface {
+	// size of terminal, read again after KEY_RESIZE
+	Size() (int, int)
+	// size of drawing window
+	Resize(lines, cols int)
+	Clear()
+	Move(line, col int)
+	CursorYX() (int, int)
+	// prints at cursor, cursor moves after text
+	Print(text string)
+	AttrOn(attr goncurses.Char)
+	AttrOff(attr goncurses.Char)
+	ColorOn(pair int16)
+	ColorOff(pair int16)
+	InitPair(pair, fg, bg int16)
+	// number of colors, 0 if terminal has no colors
+	Colors() int
+	ColorPairs() int
+	Scroll(n int)
+	ShowCursor(on bool)
+	GetChar() goncurses.Key
+	// event of last KEY_MOUSE in terminal coordinates
+	GetMouse() (MouseEvent, bool)
+	Refresh()
+	End()
+}
+
+// ----------- CURSES -----------
+
+type CursesBackend struct {
+	Win *goncurses.Window
+}
+
+func NewCursesBackend() *CursesBackend {
+	goncurses.Init()
+
+	goncurses.CBreak(true)
+	goncurses.Cursor(0)
+
+	if goncurses.HasColors() {
+		goncurses.StartColor()
+		goncurses.UseDefaultColors()
+	}
+
+	lines, cols := goncurses.StdScr().MaxYX()
+	win, _ := goncurses.NewWindow(lines, cols, 0, 0)
+
+	win.Keypad(true)
+	win.ScrollOk(true)
+	goncurses.MouseMask(MouseEvents, nil)
+
+	return &CursesBackend{win}
+}
+
+func (c *CursesBackend) Size() (int, int) {
+	return goncurses.StdScr().MaxYX()
+}
+
+func (c *CursesBackend) Resize(lines, cols int) {
+	c.Win.Resize(lines, cols)
+}
+
+func (c *CursesBackend) Clear() {
+	c.Win.Clear()
+}
+
+func (c *CursesBackend) Move(line, col int) {
+	c.Win.Move(line, col)
+}
+
+func (c *CursesBackend) CursorYX() (int, int) {
+	return c.Win.CursorYX()
+}
+
+func (c *CursesBackend) Print(text string) {
+	c.Win.Print(text)
+}
+
+func (c *CursesBackend) AttrOn(attr goncurses.Char) {
+	c.Win.AttrOn(attr)
+}
+
+func (c *CursesBackend) AttrOff(attr goncurses.Char) {
+	c.Win.AttrOff(attr)
+}
+
+func (c *CursesBackend) ColorOn(pair int16) {
+	c.Win.ColorOn(pair)
+}
+
+func (c *CursesBackend) ColorOff(pair int16) {
+	c.Win.ColorOff(pair)
+}
+
+func (c *CursesBackend) InitPair(pair, fg, bg int16) {
+	goncurses.InitPair(pair, fg, bg)
+}
+
+func (c *CursesBackend) Colors() int {
+	if goncurses.HasColors() {
+		return goncurses.Colors()
+	}
+	return 0
+}
+
+func (c *CursesBackend) ColorPairs() int {
+	return goncurses.ColorPairs()
+}
+
+func (c *CursesBackend) Scroll(n int) {
+	c.Win.Scroll(n)
+}
+
+func (c *CursesBackend) ShowCursor(on bool) {
+	if on {
+		goncurses.Cursor(1)
+	} else {
+		goncurses.Cursor(0)
+	}
+}
+
+func (c *CursesBackend) GetChar() goncurses.Key {
+	return c.Win.GetChar()
+}
+
+func (c *CursesBackend) GetMouse() (MouseEvent, bool) {
+	event := goncurses.GetMouse()
+	if event == nil {
+		return MouseEvent{}, false
+	}
+	ev := MouseEvent{Line: event.Y, Col: event.X}
+	switch {
+	case event.State&goncurses.M_B1_DBL_CLICKED != 0:
+		ev.Action = MouseDoubleClick
+	case event.State&goncurses.M_B1_CLICKED != 0:
+		ev.Action = MouseClick
+	case event.State&goncurses.M_B4_PRESSED != 0:
+		ev.Action = MouseWheelUp
+	case event.State&M_B5_PRESSED != 0:
+		ev.Action = MouseWheelDown
+	default:
+		return ev, false
+	}
+	return ev, true
+}
+
+func (c *CursesBackend) Refresh() {
+	c.Win.Refresh()
+}
+
+func (c *CursesBackend) End() {
+	goncurses.CBreak(false)
+	goncurses.End()
+}
@@ -0,0 +1,256 @@
+package console
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/bookstore-go/download"
+)
+
+// terminal cell showing two vertical pixels with upper half block: foreground is top pixel, background is bottom pixel
+type CoverCell struct {
+	Top    int16
+	Bottom int16
+}
+
+const HalfBlock = "▀"
+
+// from darkest to brightest
+const AsciiRamp = " .:-=+*#%@"
+
+// columns of cover drawn next to book metadata
+func CoverWidth(ctx *ScreenContext) int {
+	width := ctx.LogicCols * ctx.ColSize / 4
+	if width > 40 {
+		width = 40
+	}
+	return width
+}
+
+// rows of cover cols wide; a terminal cell is about twice as high as wide. 0 for an empty image
+func CoverRows(img image.Image, cols int) int {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+	rows := (bounds.Dy()*cols/bounds.Dx() + 1) / 2
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
+
+// nil for an empty image
+func HalfBlockCells(img image.Image, cols int) [][]CoverCell {
+	rows := CoverRows(img, cols)
+	scaled, err := download.ResizeImage(img, cols, rows*2)
+	if err != nil {
+		return nil
+	}
+
+	cells := make([][]CoverCell, rows)
+	for y := 0; y < rows; y += 1 {
+		cells[y] = make([]CoverCell, cols)
+		for x := 0; x < cols; x += 1 {
+			cells[y][x] = CoverCell{Color256(scaled.At(x, 2*y)), Color256(scaled.At(x, 2*y+1))}
+		}
+	}
+	return cells
+}
+
+// nil for an empty image
+func AsciiArt(img image.Image, cols int) []string {
+	rows := CoverRows(img, cols)
+	scaled, err := download.ResizeImage(img, cols, rows)
+	if err != nil {
+		return nil
+	}
+
+	lines := make([]string, rows)
+	for y := 0; y < rows; y += 1 {
+		line := make([]byte, cols)
+		for x := 0; x < cols; x += 1 {
+			gray := color.GrayModel.Convert(scaled.At(x, y)).(color.Gray)
+			line[x] = AsciiRamp[int(gray.Y)*len(AsciiRamp)/256]
+		}
+		lines[y] = string(line)
+	}
+	return lines
+}
+
+// nearest color of xterm 256 colors palette: 6x6x6 color cube (16-231) or gray ramp (232-255)
+func Color256(c color.Color) int16 {
+	r, g, b, _ := c.RGBA()
+	r, g, b = r>>8, g>>8, b>>8
+
+	cube := func(v uint32) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return int((v - 35) / 40)
+	}
+	level := func(i int) uint32 {
+		if i == 0 {
+			return 0
+		}
+		return uint32(55 + i*40)
+	}
+	dist := func(r1, g1, b1 uint32) uint32 {
+		sq := func(a, b uint32) uint32 {
+			if a > b {
+				return (a - b) * (a - b)
+			}
+			return (b - a) * (b - a)
+		}
+		return sq(r, r1) + sq(g, g1) + sq(b, b1)
+	}
+
+	cr, cg, cb := cube(r), cube(g), cube(b)
+	cube_index := 16 + 36*cr + 6*cg + cb
+	cube_dist := dist(level(cr), level(cg), level(cb))
+
+	avg := (r + g + b) / 3
+	gray := 23
+	if avg < 238 {
+		gray = 0
+		if avg > 8 {
+			gray = int(avg-8) / 10
+		}
+	}
+	gray_level := uint32(8 + gray*10)
+	if dist(gray_level, gray_level, gray_level) < cube_dist {
+		return int16(232 + gray)
+	}
+	return int16(cube_index)
+}
+
+// xterm color with each rgb component of color cube rounded to a multiple of step (1 to 5),
+// gray ramp rounded to a multiple of 4*step
+func ReduceColor(c int16, step int) int16 {
+	round := func(v, step, max int) int {
+		v = (v + step/2) / step * step
+		if v > max {
+			v = max
+		}
+		return v
+	}
+	if c >= 232 {
+		return int16(232 + round(int(c)-232, 4*step, 23))
+	}
+	if c >= 16 {
+		i := int(c) - 16
+		r, g, b := i/36, i/6%6, i%6
+		return int16(16 + 36*round(r, step, 5) + 6*round(g, step, 5) + round(b, step, 5))
+	}
+	return c
+}
+
+// cells with colors reduced by ReduceColor
+func ReduceCells(cells [][]CoverCell, step int) [][]CoverCell {
+	reduced := make([][]CoverCell, len(cells))
+	for y, row := range cells {
+		reduced[y] = make([]CoverCell, len(row))
+		for x, cell := range row {
+			reduced[y][x] = CoverCell{ReduceColor(cell.Top, step), ReduceColor(cell.Bottom, step)}
+		}
+	}
+	return reduced
+}
+
+// number of pairs the terminal has, not more than pair numbers of curses
+func (t *Terminal) MaxPairs() int {
+	max_pairs := t.Backend.ColorPairs()
+	if max_pairs > 32767 {
+		max_pairs = 32767
+	}
+	return max_pairs
+}
+
+// number of new pairs needed to draw cells
+func (t *Terminal) NewPairs(cells [][]CoverCell) int {
+	keys := make(map[[2]int16]bool)
+	for _, row := range cells {
+		for _, cell := range row {
+			key := [2]int16{cell.Top, cell.Bottom}
+			if _, ok := t.Pairs[key]; !ok {
+				keys[key] = true
+			}
+		}
+	}
+	return len(keys)
+}
+
+// color pair of fg/bg colors, allocated on demand; 0 if all pairs are used.
+// A pair is never redefined: cells drawn with it would change color
+func (t *Terminal) ColorPair(fg, bg int16) int16 {
+	key := [2]int16{fg, bg}
+	if pair, ok := t.Pairs[key]; ok {
+		return pair
+	}
+	if t.Pairs == nil {
+		t.Pairs = make(map[[2]int16]int16)
+	}
+	if int(t.NextPair) >= t.MaxPairs() {
+		return 0
+	}
+
+	pair := t.NextPair
+	t.Backend.InitPair(pair, fg, bg)
+	t.Pairs[key] = pair
+	t.NextPair += 1
+	return pair
+}
+
+// pairs allocated after theme styles can be defined again; only when nothing drawn with them is on screen
+func (t *Terminal) FreePairs() {
+	for key, pair := range t.Pairs {
+		if pair >= t.FirstPair {
+			delete(t.Pairs, key)
+		}
+	}
+	t.NextPair = t.FirstPair
+}
+
+// draws cover at line, col; 256 colors half blocks or ascii art on limited terminals.
+// Colors are reduced when free pairs are missing, ascii art is drawn if they are still missing.
+// Returns number of lines drawn
+func (t *Terminal) DrawCover(line, col int, img image.Image, cols int) int {
+	w := t.Backend
+
+	var cells [][]CoverCell
+	if t.Colors >= 256 {
+		cells = HalfBlockCells(img, cols)
+		free := t.MaxPairs() - int(t.NextPair)
+		for step := 2; step <= 5 && t.NewPairs(cells) > free; step += 1 {
+			cells = ReduceCells(HalfBlockCells(img, cols), step)
+		}
+		if t.NewPairs(cells) > free {
+			cells = nil
+		}
+	}
+
+	if cells != nil {
+		for y, row := range cells {
+			for x, cell := range row {
+				pair := t.ColorPair(cell.Top, cell.Bottom)
+				w.ColorOn(pair)
+				w.Move(line+y, col+x)
+				w.Print(HalfBlock)
+				w.ColorOff(pair)
+			}
+		}
+		w.Refresh()
+		return len(cells)
+	}
+
+	lines := AsciiArt(img, cols)
+	for y, s := range lines {
+		w.Move(line+y, col)
+		w.Print(s)
+	}
+	w.Refresh()
+	return len(lines)
+}
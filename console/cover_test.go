@@ -0,0 +1,162 @@
+package console_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/bookstore-go/console"
+)
+
+func TestColor256(t *testing.T) {
+	colors := []struct {
+		c    color.Color
+		want int16
+	}{
+		{color.RGBA{0, 0, 0, 255}, 16},
+		{color.RGBA{255, 255, 255, 255}, 231},
+		{color.RGBA{255, 0, 0, 255}, 196},
+		{color.RGBA{0, 255, 0, 255}, 46},
+		{color.RGBA{0, 0, 255, 255}, 21},
+		{color.RGBA{128, 128, 128, 255}, 244},
+	}
+	for _, tc := range colors {
+		if got := console.Color256(tc.c); got != tc.want {
+			t.Fatalf("Color mismatch for %v (want %d, got %d)\n", tc.c, tc.want, got)
+		}
+	}
+}
+
+// 4x8 image: top half white, bottom half black
+func testCover() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 8))
+	for y := 0; y < 8; y += 1 {
+		for x := 0; x < 4; x += 1 {
+			if y < 4 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
+}
+
+func TestHalfBlockCells(t *testing.T) {
+	cells := console.HalfBlockCells(testCover(), 4)
+	if len(cells) != 4 || len(cells[0]) != 4 {
+		t.Fatalf("4x4 cells expected (got %dx%d)\n", len(cells[0]), len(cells))
+	}
+	if cells[0][0].Top != 231 || cells[0][0].Bottom != 231 {
+		t.Fatalf("Top cell should be white (got %v)\n", cells[0][0])
+	}
+	if cells[3][3].Top != 16 || cells[3][3].Bottom != 16 {
+		t.Fatalf("Bottom cell should be black (got %v)\n", cells[3][3])
+	}
+}
+
+func TestEmptyCover(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 0, 8))
+	if cells := console.HalfBlockCells(img, 4); cells != nil {
+		t.Fatalf("No cells expected (got %v)\n", cells)
+	}
+	if lines := console.AsciiArt(img, 4); lines != nil {
+		t.Fatalf("No lines expected (got %q)\n", lines)
+	}
+}
+
+func TestAsciiArt(t *testing.T) {
+	lines := console.AsciiArt(testCover(), 4)
+	if len(lines) != 4 {
+		t.Fatalf("4 lines expected (got %d)\n", len(lines))
+	}
+	if lines[0] != "@@@@" || lines[3] != "    " {
+		t.Fatalf("Ascii art mismatch (got %q)\n", lines)
+	}
+}
+
+func TestReduceColor(t *testing.T) {
+	colors := []struct {
+		c    int16
+		step int
+		want int16
+	}{
+		{196, 1, 196},
+		{16 + 36*4 + 6*3 + 1, 2, 16 + 36*4 + 6*4 + 2},
+		{16 + 36*4 + 6*3 + 1, 5, 16 + 36*5 + 6*5},
+		{232 + 13, 2, 232 + 16},
+		{232 + 22, 5, 232 + 20},
+		{3, 5, 3},
+	}
+	for _, tc := range colors {
+		if got := console.ReduceColor(tc.c, tc.step); got != tc.want {
+			t.Fatalf("Reduced color mismatch for %d, step %d (want %d, got %d)\n", tc.c, tc.step, tc.want, got)
+		}
+	}
+}
+
+func TestColorPairExhausted(t *testing.T) {
+	vs := console.NewVirtualScreen(24, 80)
+	vs.NbColors = 256
+	tty := console.NewBackendTerminal(vs)
+
+	first := tty.ColorPair(100, 101)
+	for i := int16(0); i < 300; i += 1 {
+		tty.ColorPair(i, 255-i%256)
+	}
+	if pair := tty.ColorPair(1, 2); pair != 0 {
+		t.Fatalf("No pair expected when all are used (got %d)\n", pair)
+	}
+	if vs.Pairs[first] != [2]int16{100, 101} {
+		t.Fatalf("Pair should not be redefined (got %v)\n", vs.Pairs[first])
+	}
+
+	// pairs are freed with screen
+	tty.ClearScreen()
+	if pair := tty.ColorPair(1, 2); pair != tty.FirstPair {
+		t.Fatalf("First pair expected after clear (got %d)\n", pair)
+	}
+}
+
+// image with a color per pixel
+func noisyCover() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 80))
+	for y := 0; y < 80; y += 1 {
+		for x := 0; x < 40; x += 1 {
+			img.Set(x, y, color.RGBA{uint8(x * 6), uint8(y * 3), uint8((x * y) % 256), 255})
+		}
+	}
+	return img
+}
+
+func TestDrawCoverPairs(t *testing.T) {
+	vs := console.NewVirtualScreen(40, 80)
+	vs.NbColors = 256
+	tty := console.NewBackendTerminal(vs)
+
+	tty.NewScreen(&runScreen{Test: func(tty *console.Terminal) {
+		// colors are reduced to fit in pairs
+		tty.DrawCover(0, 0, noisyCover(), 40)
+		if int(tty.NextPair) > 256 {
+			t.Fatalf("Too many pairs used (got %d)\n", tty.NextPair)
+		}
+		for x := 0; x < 40; x += 1 {
+			if cell := vs.Cells[0][x]; cell.Pair == 0 || cell.Text != console.HalfBlock {
+				t.Fatalf("Half block with colors expected (got %v)\n", cell)
+			}
+		}
+
+		// no pair left for another cover: ascii art
+		tty.ClearScreen()
+		for i := int16(0); int(tty.NextPair) < 256; i += 1 {
+			tty.ColorPair(i, 255)
+		}
+		red := image.NewRGBA(image.Rect(0, 0, 4, 8))
+		draw.Draw(red, red.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+		tty.DrawCover(0, 0, red, 4)
+		if cell := vs.Cells[0][0]; cell.Pair != 0 || cell.Text == console.HalfBlock {
+			t.Fatalf("Ascii art expected (got %v)\n", cell)
+		}
+	}})
+}
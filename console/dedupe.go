@@ -0,0 +1,178 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bookstore-go/download"
+	"github.com/bookstore-go/utils"
+	"github.com/rthornton128/goncurses"
+)
+
+// review of duplicate books clusters: select book to keep and merge others into it.
+// Excluded books are not merged
+type DedupeScreen struct {
+	Tty      *Terminal
+	Clusters [][]*utils.Book
+	Current  int
+	Excluded map[int]bool
+	List     *ListView
+	Status   *StatusBar
+}
+
+// first line of books of cluster
+const DedupeFirstLine = 3
+
+func DedupeLine(book *utils.Book) string {
+	return fmt.Sprintf("%6d  %-40.40s  %-25.25s  %4d  %s", book.Id, book.Title, book.Authors, book.Year, book.Isbn)
+}
+
+func (ds *DedupeScreen) Init(tty *Terminal, ctx *ScreenContext) {
+	ds.Tty = tty
+	ds.Excluded = make(map[int]bool)
+	ds.Status = &StatusBar{Help: "UP/DOWN: book to keep   " + Keys.Names(ActionToggle) + ": exclude   " + Keys.Hints(ActionMerge, ActionNext, ActionPrevious, ActionQuit, ActionHelp)}
+	ds.List = NewListView(0, tty.Lines-DedupeFirstLine-1, tty.Cols, func(index int) string {
+		book := ds.Clusters[ds.Current][index]
+		if ds.Excluded[book.Id] {
+			return "x " + DedupeLine(book)
+		}
+		return "  " + DedupeLine(book)
+	})
+	ds.SetCluster(0)
+	tty.ClearScreen()
+}
+
+// shows cluster index, first book selected
+func (ds *DedupeScreen) SetCluster(index int) {
+	if len(ds.Clusters) == 0 {
+		ds.Current = 0
+		ds.List.SetCount(0)
+		ds.Status.Help = fmt.Sprintf("No duplicate books. Press %s to quit", Keys.Names(ActionQuit))
+		return
+	}
+	ds.Current = (index + len(ds.Clusters)) % len(ds.Clusters)
+	ds.List.SetCount(len(ds.Clusters[ds.Current]))
+	ds.List.Select(0)
+}
+
+func (ds *DedupeScreen) Run() {
+	ds.OnRefresh(0, 0)
+	ds.Tty.BeginRead()
+}
+
+func (ds *DedupeScreen) OnScroll(y int) {
+	// list is scrolled by list view
+}
+
+func (ds *DedupeScreen) Crumb() string {
+	if len(ds.Clusters) == 0 {
+		return "Duplicate books"
+	}
+	return fmt.Sprintf("Duplicate books %d/%d", ds.Current+1, len(ds.Clusters))
+}
+
+func (ds *DedupeScreen) OnRefresh(lines, cols int) {
+	tty := ds.Tty
+	if lines > 0 {
+		ds.List.Resize(lines-DedupeFirstLine-1, cols)
+	}
+	tty.ClearScreen()
+	tty.DrawHeader()
+
+	if len(ds.Clusters) > 0 {
+		tty.PrintStyle(DedupeFirstLine-1, 0, fmt.Sprintf("  %6s  %-40s  %-25s  %4s  %s", "Id", "Title", "Authors", "Year", "ISBN"), StyleLabel)
+		ds.List.Draw(tty, DedupeFirstLine, 0)
+	}
+	ds.Status.Draw(tty)
+}
+
+// actions of duplicates review
+var DedupeActions = append([]Action{ActionMerge, ActionToggle, ActionNext, ActionPrevious, ActionQuit}, ListActions...)
+
+func (ds *DedupeScreen) HelpActions() []Action {
+	return DedupeActions
+}
+
+// click selects book to keep
+func (ds *DedupeScreen) OnMouse(ev MouseEvent) {
+	if len(ds.Clusters) > 0 {
+		ds.List.OnMouse(ev, DedupeFirstLine, 0)
+		ds.OnRefresh(0, 0)
+	}
+}
+
+func (ds *DedupeScreen) OnKey(key goncurses.Key) {
+	if Keys.Is(key, ActionQuit) {
+		ds.Tty.EndRead()
+		return
+	}
+	if len(ds.Clusters) == 0 {
+		return
+	}
+
+	cluster := ds.Clusters[ds.Current]
+	ds.Status.Clear()
+
+	switch Keys.Action(key, DedupeActions...) {
+	case ActionNext:
+		ds.SetCluster(ds.Current + 1)
+	case ActionPrevious:
+		ds.SetCluster(ds.Current - 1)
+	case ActionToggle:
+		book := cluster[ds.List.Selected]
+		ds.Excluded[book.Id] = !ds.Excluded[book.Id]
+	case ActionMerge:
+		keep := cluster[ds.List.Selected]
+		if ds.Excluded[keep.Id] {
+			ds.Status.Err = fmt.Errorf("Book %d to keep is excluded", keep.Id)
+			break
+		}
+		var ids []int
+		var excluded []*utils.Book
+		for _, book := range cluster {
+			if ds.Excluded[book.Id] {
+				excluded = append(excluded, book)
+			} else if book.Id != keep.Id {
+				ids = append(ids, book.Id)
+			}
+		}
+		if len(ids) == 0 {
+			ds.Status.Err = errors.New("No book to merge")
+			break
+		}
+		if !ds.Tty.Confirm(fmt.Sprintf("Merge books %v into book %d?", ids, keep.Id), false) {
+			break
+		}
+		err := utils.MergeBooks(keep.Id, ids)
+		if err != nil {
+			ds.Status.Err = fmt.Errorf("Merge failed: %v", err)
+			break
+		}
+		for _, id := range ids {
+			if err = download.Covers().MergeCover(keep.Id, id); err != nil {
+				ds.Status.Err = fmt.Errorf("Cover of book %d not merged: %v", id, err)
+			}
+		}
+		// excluded books stay to review if they are still duplicates
+		if len(excluded) > 1 {
+			ds.Clusters[ds.Current] = excluded
+			for _, book := range excluded {
+				delete(ds.Excluded, book.Id)
+			}
+		} else {
+			ds.Clusters = append(ds.Clusters[:ds.Current], ds.Clusters[ds.Current+1:]...)
+		}
+		ds.SetCluster(ds.Current)
+		ds.Status.Message = fmt.Sprintf("Books %v merged into book %d", ids, keep.Id)
+	default:
+		ds.List.OnKey(key)
+	}
+	ds.OnRefresh(0, 0)
+}
+
+// review duplicate books in terminal
+func DedupeLoop(clusters [][]*utils.Book) {
+	tty := NewTerminal()
+	tty.NewScreen(&DedupeScreen{Clusters: clusters})
+	tty.End()
+}
@@ -0,0 +1,541 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bookstore-go/utils"
+	"github.com/rthornton128/goncurses"
+)
+
+// first line of forms and lists of editing screens
+const EditFirstLine = 2
+
+// columns of form labels
+const EditLabelWidth = 16
+
+// ----------- BOOK -----------
+
+// line breaks of description shown in its one line text field
+const LineBreakMark = "↵"
+
+// creates book if Book is nil, edits Book otherwise
+type BookEditScreen struct {
+	Tty    *Terminal
+	Book   *utils.Book
+	Form   *Form
+	Status *StatusBar
+	Saved  bool
+}
+
+func (be *BookEditScreen) Init(tty *Terminal, ctx *ScreenContext) {
+	be.Tty = tty
+	if be.Book == nil {
+		be.Book = &utils.Book{}
+	}
+	year := ""
+	if be.Book.Year > 0 {
+		year = strconv.Itoa(be.Book.Year)
+	}
+	var tags []string
+	if be.Book.Id > 0 {
+		tags, _ = utils.GetBookTags(be.Book.Id)
+	}
+	be.Form = &Form{Tty: tty, Line: EditFirstLine, LabelWidth: EditLabelWidth, Fields: []*TextField{
+		NewTextField("Title:", be.Book.Title, 1),
+		NewTextField("Authors:", be.Book.Authors, 1),
+		NewTextField("Year:", year, 6),
+		NewTextField("Description:", strings.ReplaceAll(be.Book.Description, "\n", LineBreakMark), 1),
+		NewTextField("Tags:", strings.Join(tags, ", "), 1),
+	}}
+	be.Layout()
+	be.Status = &StatusBar{Help: "TAB/UP/DOWN: next field   " + Keys.Hints(ActionSave, ActionCancel, ActionHelp)}
+	tty.ClearScreen()
+}
+
+func (be *BookEditScreen) Run() {
+	be.Tty.ShowCursor(true)
+	be.OnRefresh(0, 0)
+	be.Tty.BeginRead()
+	be.Tty.ShowCursor(false)
+}
+
+func (be *BookEditScreen) OnScroll(y int) {
+	// no scroll
+}
+
+// text fields are as wide as terminal
+func (be *BookEditScreen) Layout() {
+	width := be.Tty.Cols - EditLabelWidth - 1
+	for _, i := range []int{0, 1, 3, 4} {
+		be.Form.Fields[i].SetWidth(width)
+	}
+}
+
+func (be *BookEditScreen) Crumb() string {
+	if be.Book.Id == 0 {
+		return "New book"
+	}
+	return fmt.Sprintf("Edit book %d", be.Book.Id)
+}
+
+func (be *BookEditScreen) OnRefresh(lines, cols int) {
+	tty := be.Tty
+	if lines > 0 {
+		be.Layout()
+	}
+	tty.ClearScreen()
+	tty.DrawHeader()
+	tty.PrintStyle(EditFirstLine+len(be.Form.Fields)+1, 0, "Description is edited on one line, "+LineBreakMark+" marks line breaks", StyleLabel)
+	be.Status.Draw(tty)
+	be.Form.Draw()
+}
+
+// actions of forms, other keys edit fields
+var FormActions = []Action{ActionSave, ActionCancel}
+
+func (be *BookEditScreen) HelpActions() []Action {
+	return FormActions
+}
+
+func (be *BookEditScreen) Typing() bool {
+	return true
+}
+
+func (be *BookEditScreen) OnKey(key goncurses.Key) {
+	switch Keys.Action(key, FormActions...) {
+	case ActionCancel:
+		be.Tty.EndRead()
+	case ActionSave:
+		err := be.Save()
+		if err == nil {
+			be.Tty.EndRead()
+			return
+		}
+		be.Status.Err = err
+		be.OnRefresh(0, 0)
+	default:
+		be.Form.OnKey(key)
+	}
+}
+
+func (be *BookEditScreen) Save() error {
+	fields := be.Form.Fields
+	title := strings.TrimSpace(fields[0].Text())
+	if len(title) == 0 {
+		return errors.New("Title is required")
+	}
+	year := 0
+	if year_str := strings.TrimSpace(fields[2].Text()); len(year_str) > 0 {
+		var err error
+		year, err = strconv.Atoi(year_str)
+		if err != nil {
+			return fmt.Errorf("Invalid year '%s'", year_str)
+		}
+	}
+
+	book := *be.Book
+	book.Title = title
+	book.Authors = strings.TrimSpace(fields[1].Text())
+	book.Year = year
+	book.Description = strings.ReplaceAll(fields[3].Text(), LineBreakMark, "\n")
+
+	// book is not saved without its tags
+	err := utils.SaveBookWithTags(&book, utils.NormalizeTags(fields[4].Text()))
+	if err != nil {
+		return err
+	}
+	*be.Book = book
+	be.Saved = true
+	return nil
+}
+
+// ----------- SUBJECTS OF BOOK -----------
+
+// assigns subjects to book: SPACE toggles subject of selected item
+type BookSubjectsScreen struct {
+	Tty      *Terminal
+	BookId   int
+	Subjects []utils.Subject
+	Assigned map[uint]bool
+	Grid     *GridView
+	Status   *StatusBar
+}
+
+// columns of subjects grid
+const SubjectColWidth = 32
+
+func (bs *BookSubjectsScreen) Init(tty *Terminal, ctx *ScreenContext) {
+	bs.Tty = tty
+	bs.Assigned = make(map[uint]bool)
+	bs.Status = &StatusBar{Help: Keys.Hints(ActionHelp, ActionToggle, ActionSave, ActionCancel)}
+
+	var err error
+	bs.Subjects, err = utils.GetAllSubjects()
+	if err == nil {
+		var assigned []utils.Subject
+		assigned, err = utils.GetBookSubjects(bs.BookId)
+		for _, sub := range assigned {
+			bs.Assigned[sub.Id] = true
+		}
+	}
+	if err != nil {
+		bs.Status.Err = err
+	}
+	bs.Grid = NewGridView(len(bs.Subjects), bs.PageSize(), tty.Cols, SubjectColWidth, bs.SubjectLine)
+	tty.ClearScreen()
+}
+
+func (bs *BookSubjectsScreen) Run() {
+	bs.OnRefresh(0, 0)
+	bs.Tty.BeginRead()
+}
+
+func (bs *BookSubjectsScreen) OnScroll(y int) {
+	// grid is scrolled by grid view
+}
+
+// number of grid lines on screen
+func (bs *BookSubjectsScreen) PageSize() int {
+	size := bs.Tty.Lines - EditFirstLine - 1
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+func (bs *BookSubjectsScreen) SubjectLine(index int) string {
+	check := " "
+	if bs.Assigned[bs.Subjects[index].Id] {
+		check = "x"
+	}
+	return fmt.Sprintf("[%s] %s", check, bs.Subjects[index].Name)
+}
+
+func (bs *BookSubjectsScreen) Crumb() string {
+	return "Subjects"
+}
+
+func (bs *BookSubjectsScreen) OnRefresh(lines, cols int) {
+	tty := bs.Tty
+	if lines > 0 {
+		bs.Grid.Resize(bs.PageSize(), cols)
+	}
+	tty.ClearScreen()
+	tty.DrawHeader()
+	bs.Grid.Draw(tty, EditFirstLine, 0)
+	bs.Status.Draw(tty)
+}
+
+// actions of subjects grid
+var BookSubjectsActions = append([]Action{ActionToggle, ActionSave, ActionCancel, ActionQuit}, GridActions...)
+
+func (bs *BookSubjectsScreen) HelpActions() []Action {
+	return BookSubjectsActions
+}
+
+// assigns or unassigns selected subject
+func (bs *BookSubjectsScreen) ToggleSelected() {
+	if bs.Grid.Selected < len(bs.Subjects) {
+		id := bs.Subjects[bs.Grid.Selected].Id
+		bs.Assigned[id] = !bs.Assigned[id]
+	}
+}
+
+// double-click assigns or unassigns subject
+func (bs *BookSubjectsScreen) OnMouse(ev MouseEvent) {
+	bs.Status.Clear()
+	if bs.Grid.OnMouse(ev, EditFirstLine, 0) {
+		bs.ToggleSelected()
+	}
+	bs.OnRefresh(0, 0)
+}
+
+func (bs *BookSubjectsScreen) OnKey(key goncurses.Key) {
+	bs.Status.Clear()
+
+	switch Keys.Action(key, BookSubjectsActions...) {
+	case ActionCancel, ActionQuit:
+		bs.Tty.EndRead()
+		return
+	case ActionSave:
+		var ids []uint
+		for _, sub := range bs.Subjects {
+			if bs.Assigned[sub.Id] {
+				ids = append(ids, sub.Id)
+			}
+		}
+		err := utils.SetBookSubjects(bs.BookId, ids)
+		if err == nil {
+			bs.Tty.EndRead()
+			return
+		}
+		bs.Status.Err = err
+	case ActionToggle:
+		bs.ToggleSelected()
+	default:
+		bs.Grid.OnKey(key)
+	}
+	bs.OnRefresh(0, 0)
+}
+
+// ----------- LINKS OF BOOK -----------
+
+// files of book in file stores (BOOKS_LINKS)
+type BookLinksScreen struct {
+	Tty    *Terminal
+	BookId int
+	Links  []*utils.BookDownload
+	List   *ListView
+	Status *StatusBar
+}
+
+func (bl *BookLinksScreen) Init(tty *Terminal, ctx *ScreenContext) {
+	bl.Tty = tty
+	bl.Status = &StatusBar{Help: Keys.Hints(ActionHelp, ActionAdd, ActionEdit, ActionDelete, ActionQuit)}
+	bl.List = NewListView(0, tty.Lines-EditFirstLine-1, tty.Cols, func(index int) string {
+		return LinkLine(bl.Links[index])
+	})
+	bl.Load()
+	tty.ClearScreen()
+}
+
+func (bl *BookLinksScreen) Load() {
+	var err error
+	bl.Links, err = utils.GetBookLinks(bl.BookId)
+	if err != nil {
+		bl.Status.Err = err
+	}
+	bl.List.SetCount(len(bl.Links))
+}
+
+func (bl *BookLinksScreen) Run() {
+	bl.OnRefresh(0, 0)
+	bl.Tty.BeginRead()
+}
+
+func (bl *BookLinksScreen) OnScroll(y int) {
+	// list is scrolled by list view
+}
+
+func LinkLine(link *utils.BookDownload) string {
+	vendor := link.VendorCode
+	if len(link.Account) > 0 {
+		vendor += "/" + link.Account
+	}
+	return fmt.Sprintf("%5d  %-15.15s  %-30.30s  %-30.30s  %10d", link.StorageId, vendor, link.FileId, link.FileName, link.FileSize)
+}
+
+func (bl *BookLinksScreen) Crumb() string {
+	return "Files"
+}
+
+func (bl *BookLinksScreen) OnRefresh(lines, cols int) {
+	tty := bl.Tty
+	if lines > 0 {
+		bl.List.Resize(lines-EditFirstLine-1, cols)
+	}
+	tty.ClearScreen()
+	tty.DrawHeader()
+	tty.PrintStyle(EditFirstLine-1, 0, fmt.Sprintf("%5s  %-15s  %-30s  %-30s  %10s", "Store", "Vendor", "File id", "File name", "Size"), StyleLabel)
+	bl.List.Draw(tty, EditFirstLine, 0)
+	bl.Status.Draw(tty)
+}
+
+// actions of files list
+var BookLinksActions = append([]Action{ActionAdd, ActionEdit, ActionOpen, ActionDelete, ActionQuit}, ListActions...)
+
+func (bl *BookLinksScreen) HelpActions() []Action {
+	return BookLinksActions
+}
+
+func (bl *BookLinksScreen) EditSelected() {
+	if selected := bl.List.Selected; selected < len(bl.Links) {
+		link := *bl.Links[selected]
+		bl.Tty.NewScreen(&LinkEditScreen{Link: &link, OldStoreId: link.StorageId})
+		bl.Load()
+	}
+}
+
+// double-click edits file
+func (bl *BookLinksScreen) OnMouse(ev MouseEvent) {
+	bl.Status.Clear()
+	if bl.List.OnMouse(ev, EditFirstLine, 0) {
+		bl.EditSelected()
+	}
+	bl.OnRefresh(0, 0)
+}
+
+func (bl *BookLinksScreen) OnKey(key goncurses.Key) {
+	bl.Status.Clear()
+	selected := bl.List.Selected
+
+	switch Keys.Action(key, BookLinksActions...) {
+	case ActionQuit:
+		bl.Tty.EndRead()
+		return
+	case ActionAdd:
+		bl.Tty.NewScreen(&LinkEditScreen{Link: &utils.BookDownload{BookId: bl.BookId}})
+		bl.Load()
+	case ActionEdit, ActionOpen:
+		bl.EditSelected()
+	case ActionDelete:
+		if selected < len(bl.Links) {
+			link := bl.Links[selected]
+			if !bl.Tty.Confirm(fmt.Sprintf("Delete file '%s' of store %d?", link.FileName, link.StorageId), false) {
+				break
+			}
+			err := utils.DeleteBookLink(link.BookId, link.StorageId)
+			if err != nil {
+				bl.Status.Err = err
+			}
+			bl.Load()
+		}
+	default:
+		bl.List.OnKey(key)
+	}
+	bl.OnRefresh(0, 0)
+}
+
+// adds link if OldStoreId is 0, edits link otherwise
+type LinkEditScreen struct {
+	Tty        *Terminal
+	Link       *utils.BookDownload
+	OldStoreId int
+	Vendors    []*utils.StorageVendor
+	Form       *Form
+	Status     *StatusBar
+}
+
+func (le *LinkEditScreen) Init(tty *Terminal, ctx *ScreenContext) {
+	le.Tty = tty
+	le.Vendors, _ = utils.GetVendors()
+
+	store, size := "", ""
+	if le.Link.StorageId > 0 {
+		store = strconv.Itoa(le.Link.StorageId)
+	}
+	if le.Link.FileSize > 0 {
+		size = strconv.Itoa(le.Link.FileSize)
+	}
+	le.Form = &Form{Tty: tty, Line: EditFirstLine, LabelWidth: EditLabelWidth, Fields: []*TextField{
+		NewTextField("Store:", store, 6),
+		NewTextField("File id:", le.Link.FileId, 1),
+		NewTextField("File name:", le.Link.FileName, 1),
+		NewTextField("File size:", size, 12),
+	}}
+	le.Layout()
+	le.Status = &StatusBar{Help: "TAB/UP/DOWN: next field   " + Keys.Hints(ActionSave, ActionCancel, ActionHelp)}
+	tty.ClearScreen()
+}
+
+func (le *LinkEditScreen) Run() {
+	le.Tty.ShowCursor(true)
+	le.OnRefresh(0, 0)
+	le.Tty.BeginRead()
+	le.Tty.ShowCursor(false)
+}
+
+func (le *LinkEditScreen) OnScroll(y int) {
+	// no scroll
+}
+
+func (le *LinkEditScreen) Layout() {
+	width := le.Tty.Cols - EditLabelWidth - 1
+	le.Form.Fields[1].SetWidth(width)
+	le.Form.Fields[2].SetWidth(width)
+}
+
+func (le *LinkEditScreen) Crumb() string {
+	if le.OldStoreId == 0 {
+		return "New file"
+	}
+	return fmt.Sprintf("File %d", le.OldStoreId)
+}
+
+func (le *LinkEditScreen) OnRefresh(lines, cols int) {
+	tty := le.Tty
+	if lines > 0 {
+		le.Layout()
+	}
+	tty.ClearScreen()
+	tty.DrawHeader()
+
+	// stores to choose from
+	line := EditFirstLine + len(le.Form.Fields) + 1
+	tty.PrintStyle(line, 0, "Stores:", StyleLabel)
+	for i, v := range le.Vendors {
+		tty.CursorAddress(line+i+1, 2)
+		tty.Printf("%5d  %s (%s) %s", v.Id, v.VendorName, v.VendorCode, v.Account)
+	}
+	le.Status.Draw(tty)
+	le.Form.Draw()
+}
+
+func (le *LinkEditScreen) HelpActions() []Action {
+	return FormActions
+}
+
+func (le *LinkEditScreen) Typing() bool {
+	return true
+}
+
+func (le *LinkEditScreen) OnKey(key goncurses.Key) {
+	switch Keys.Action(key, FormActions...) {
+	case ActionCancel:
+		le.Tty.EndRead()
+	case ActionSave:
+		err := le.Save()
+		if err == nil {
+			le.Tty.EndRead()
+			return
+		}
+		le.Status.Err = err
+		le.OnRefresh(0, 0)
+	default:
+		le.Form.OnKey(key)
+	}
+}
+
+func (le *LinkEditScreen) Save() error {
+	fields := le.Form.Fields
+
+	store_id, err := strconv.Atoi(strings.TrimSpace(fields[0].Text()))
+	if err != nil {
+		return fmt.Errorf("Invalid store '%s'", fields[0].Text())
+	}
+	found := false
+	for _, v := range le.Vendors {
+		found = found || v.Id == store_id
+	}
+	if !found {
+		return fmt.Errorf("Store %d not found", store_id)
+	}
+
+	file_id := strings.TrimSpace(fields[1].Text())
+	if len(file_id) == 0 {
+		return errors.New("File id is required")
+	}
+	size := 0
+	if size_str := strings.TrimSpace(fields[3].Text()); len(size_str) > 0 {
+		size, err = strconv.Atoi(size_str)
+		if err != nil {
+			return fmt.Errorf("Invalid file size '%s'", size_str)
+		}
+	}
+
+	link := *le.Link
+	link.StorageId = store_id
+	link.FileId = file_id
+	link.FileName = strings.TrimSpace(fields[2].Text())
+	link.FileSize = size
+
+	err = utils.SaveBookLink(&link, le.OldStoreId)
+	if err != nil {
+		return err
+	}
+	*le.Link = link
+	return nil
+}
@@ -0,0 +1,187 @@
+package console_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/utils"
+)
+
+// rows returned for queries containing Match
+type fakeQuery struct {
+	Match string
+	Rows  [][]driver.Value
+}
+
+// statement executed on writable fake database
+type fakeExec struct {
+	Sql  string
+	Args []driver.Value
+	InTx bool
+}
+
+// database driver returning canned rows, first matching query wins.
+// Read only unless Writable: statements are then recorded in Execs, statements containing Fail fail
+type fakeDriver struct {
+	Queries   []fakeQuery
+	Writable  bool
+	Fail      string
+	Execs     []fakeExec
+	Commits   int
+	Rollbacks int
+}
+
+type fakeConn struct {
+	Driver *fakeDriver
+	InTx   bool
+}
+
+type fakeTx struct {
+	Conn *fakeConn
+}
+
+type fakeStmt struct {
+	Conn *fakeConn
+	Sql  string
+}
+
+type fakeRows struct {
+	Rows [][]driver.Value
+}
+
+var fakeDb = &fakeDriver{}
+
+func init() {
+	sql.Register("fakedb", fakeDb)
+}
+
+// connects utils to fake database until end of test
+func useFakeDb(t *testing.T, queries ...fakeQuery) {
+	db, err := sql.Open("fakedb", "")
+	if err != nil {
+		t.Fatalf("Cannot open fake database (got %v)\n", err)
+	}
+	*fakeDb = fakeDriver{Queries: queries}
+	utils.DatabaseObj.DbObj = db
+	utils.DatabaseObj.Connected = true
+	t.Cleanup(func() {
+		db.Close()
+		utils.DatabaseObj.DbObj = nil
+		utils.DatabaseObj.Connected = false
+	})
+}
+
+// fake database recording executed statements
+func useWritableFakeDb(t *testing.T, queries ...fakeQuery) *fakeDriver {
+	useFakeDb(t, queries...)
+	fakeDb.Writable = true
+	return fakeDb
+}
+
+// statements executed, placeholders followed by arguments: "DELETE FROM BOOKS WHERE ID=? [8]"
+func (d *fakeDriver) Statements() []string {
+	var statements []string
+	for _, e := range d.Execs {
+		statements = append(statements, fmt.Sprintf("%s %v", e.Sql, e.Args))
+	}
+	return statements
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{Driver: d}, nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c, query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	if !c.Driver.Writable {
+		return nil, errors.New("Fake database is read only")
+	}
+	c.InTx = true
+	return &fakeTx{c}, nil
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.Conn.InTx = false
+	tx.Conn.Driver.Commits += 1
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.Conn.InTx = false
+	tx.Conn.Driver.Rollbacks += 1
+	return nil
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.Conn.Driver
+	if !d.Writable {
+		return nil, errors.New("Fake database is read only")
+	}
+	if len(d.Fail) > 0 && strings.Contains(s.Sql, d.Fail) {
+		return nil, errors.New("Fake statement failure")
+	}
+	d.Execs = append(d.Execs, fakeExec{s.Sql, args, s.Conn.InTx})
+	return fakeResult{}, nil
+}
+
+// id of inserted rows
+const fakeInsertId = 100
+
+type fakeResult struct{}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return fakeInsertId, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	for _, q := range s.Conn.Driver.Queries {
+		if strings.Contains(s.Sql, q.Match) {
+			return &fakeRows{q.Rows}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.Rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.Rows[0]))
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.Rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.Rows[0])
+	r.Rows = r.Rows[1:]
+	return nil
+}
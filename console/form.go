@@ -0,0 +1,157 @@
+package console
+
+import (
+	"unicode/utf8"
+
+	"github.com/rthornton128/goncurses"
+)
+
+// single line text input, scrolled horizontally when value is wider than field.
+// Cursor and Offset (first visible rune) are rune indexes, Width is in columns
+type TextField struct {
+	Label  string
+	Value  []rune
+	Cursor int
+	Offset int
+	Width  int
+	// bytes of utf-8 character being typed: keys of multi-byte characters come one byte at a time
+	Pending []byte
+}
+
+func NewTextField(label, value string, width int) *TextField {
+	field := &TextField{Label: label, Width: width}
+	field.SetText(value)
+	return field
+}
+
+func (f *TextField) Text() string {
+	return string(f.Value)
+}
+
+// sets value, cursor at end of text
+func (f *TextField) SetText(value string) {
+	f.Value = []rune(value)
+	f.Cursor = len(f.Value)
+	f.Offset = 0
+	f.scroll()
+}
+
+func (f *TextField) SetWidth(width int) {
+	if width < 1 {
+		width = 1
+	}
+	f.Width = width
+	f.Offset = 0
+	f.scroll()
+}
+
+// edits value; returns false if key is not an editing key
+func (f *TextField) OnKey(key goncurses.Key) bool {
+	switch key {
+	case goncurses.KEY_LEFT:
+		if f.Cursor > 0 {
+			f.Cursor -= 1
+		}
+	case goncurses.KEY_RIGHT:
+		if f.Cursor < len(f.Value) {
+			f.Cursor += 1
+		}
+	case goncurses.KEY_HOME:
+		f.Cursor = 0
+	case goncurses.KEY_END:
+		f.Cursor = len(f.Value)
+	case goncurses.KEY_BACKSPACE, 127, 8:
+		if f.Cursor > 0 {
+			f.Value = append(f.Value[:f.Cursor-1], f.Value[f.Cursor:]...)
+			f.Cursor -= 1
+		}
+	case goncurses.KEY_DC:
+		if f.Cursor < len(f.Value) {
+			f.Value = append(f.Value[:f.Cursor], f.Value[f.Cursor+1:]...)
+		}
+	default:
+		if key < 32 || key > 255 {
+			return false
+		}
+		f.Pending = append(f.Pending, byte(key))
+		if !utf8.FullRune(f.Pending) {
+			return true
+		}
+		r, _ := utf8.DecodeRune(f.Pending)
+		f.Pending = nil
+		if r == utf8.RuneError {
+			return true
+		}
+		f.Value = append(f.Value[:f.Cursor], append([]rune{r}, f.Value[f.Cursor:]...)...)
+		f.Cursor += 1
+	}
+	f.scroll()
+	return true
+}
+
+// keeps cursor in visible part of value: columns before cursor and character under cursor fit in width
+func (f *TextField) scroll() {
+	if f.Cursor < f.Offset {
+		f.Offset = f.Cursor
+	}
+	cursor_width := 1
+	if f.Cursor < len(f.Value) && RuneWidth(f.Value[f.Cursor]) > 1 {
+		cursor_width = RuneWidth(f.Value[f.Cursor])
+	}
+	for f.Offset < f.Cursor && f.CursorColumn()+cursor_width > f.Width {
+		f.Offset += 1
+	}
+}
+
+// column of cursor in field
+func (f *TextField) CursorColumn() int {
+	return StringWidth(string(f.Value[f.Offset:f.Cursor]))
+}
+
+// visible part of value padded to field width; wide characters are not split
+func (f *TextField) Visible() string {
+	return FitText(string(f.Value[f.Offset:]), f.Width)
+}
+
+// text fields one per line from Line; values start at column LabelWidth
+type Form struct {
+	Tty        *Terminal
+	Line       int
+	LabelWidth int
+	Fields     []*TextField
+	Focus      int
+}
+
+func (form *Form) Draw() {
+	for i, field := range form.Fields {
+		form.Tty.PrintStyle(form.Line+i, 0, field.Label, StyleLabel)
+		form.DrawField(i)
+	}
+	form.PlaceCursor()
+}
+
+func (form *Form) DrawField(i int) {
+	form.Tty.PrintAttr(form.Line+i, form.LabelWidth, form.Fields[i].Visible(), goncurses.A_UNDERLINE)
+}
+
+func (form *Form) PlaceCursor() {
+	field := form.Fields[form.Focus]
+	form.Tty.CursorAddress(form.Line+form.Focus, form.LabelWidth+field.CursorColumn())
+}
+
+// moves between fields (TAB, UP, DOWN, RETURN) or edits field with focus; returns false if key is not handled
+func (form *Form) OnKey(key goncurses.Key) bool {
+	switch key {
+	case goncurses.KEY_TAB, goncurses.KEY_DOWN, goncurses.KEY_RETURN:
+		form.Focus = (form.Focus + 1) % len(form.Fields)
+	case goncurses.KEY_BTAB, goncurses.KEY_UP:
+		form.Focus = (form.Focus + len(form.Fields) - 1) % len(form.Fields)
+	default:
+		if !form.Fields[form.Focus].OnKey(key) {
+			return false
+		}
+		form.DrawField(form.Focus)
+	}
+	form.PlaceCursor()
+	return true
+}
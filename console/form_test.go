@@ -0,0 +1,103 @@
+package console_test
+
+import (
+	"testing"
+
+	"github.com/bookstore-go/console"
+	"github.com/rthornton128/goncurses"
+)
+
+func typeText(field *console.TextField, text string) {
+	for _, b := range []byte(text) {
+		field.OnKey(goncurses.Key(b))
+	}
+}
+
+func TestTextFieldEdit(t *testing.T) {
+	field := console.NewTextField("Title:", "Go", 10)
+
+	typeText(field, " in Action")
+	if field.Text() != "Go in Action" {
+		t.Fatalf("Text mismatch (got '%s')\n", field.Text())
+	}
+
+	field.OnKey(goncurses.KEY_HOME)
+	field.OnKey(goncurses.KEY_DC)
+	field.OnKey(goncurses.KEY_DC)
+	typeText(field, "Rust")
+	field.OnKey(goncurses.KEY_END)
+	field.OnKey(goncurses.KEY_BACKSPACE)
+	if field.Text() != "Rust in Actio" {
+		t.Fatalf("Text mismatch (got '%s')\n", field.Text())
+	}
+
+	if field.OnKey(goncurses.KEY_F2) {
+		t.Fatal("F2 is not an editing key\n")
+	}
+}
+
+func TestTextFieldUtf8(t *testing.T) {
+	field := console.NewTextField("Auteur:", "", 10)
+	typeText(field, "Éric Lévénez")
+	if field.Text() != "Éric Lévénez" || len(field.Value) != 12 {
+		t.Fatalf("Text mismatch (got '%s', %d runes)\n", field.Text(), len(field.Value))
+	}
+}
+
+func TestTextFieldScroll(t *testing.T) {
+	field := console.NewTextField("Title:", "The Go Programming Language", 10)
+	if field.Visible() != " Language " {
+		t.Fatalf("End of text should be visible (got '%s')\n", field.Visible())
+	}
+	if field.Cursor-field.Offset != 9 {
+		t.Fatalf("Cursor should be on last column (got %d)\n", field.Cursor-field.Offset)
+	}
+
+	field.OnKey(goncurses.KEY_HOME)
+	if field.Visible() != "The Go Pro" || field.Offset != 0 {
+		t.Fatalf("Start of text should be visible (got '%s')\n", field.Visible())
+	}
+
+	short := console.NewTextField("Year:", "2015", 6)
+	if short.Visible() != "2015  " {
+		t.Fatalf("Value should be padded (got '%s')\n", short.Visible())
+	}
+}
+
+func TestTextFieldSetWidth(t *testing.T) {
+	field := console.NewTextField("Title:", "abcdef", 3)
+	if field.Visible() != "ef " {
+		t.Fatalf("End of text should be visible (got '%s')\n", field.Visible())
+	}
+	field.SetWidth(8)
+	if field.Visible() != "abcdef  " || field.Offset != 0 {
+		t.Fatalf("Whole text should be visible after resize (got '%s')\n", field.Visible())
+	}
+}
+
+func TestTextFieldWide(t *testing.T) {
+	field := console.NewTextField("Titre:", "日本語テキスト", 6)
+	if field.Visible() != "スト  " || field.CursorColumn() != 4 {
+		t.Fatalf("End of text should be visible (got '%s', cursor column %d)\n", field.Visible(), field.CursorColumn())
+	}
+
+	field.OnKey(goncurses.KEY_HOME)
+	if field.Visible() != "日本語" || field.CursorColumn() != 0 {
+		t.Fatalf("Start of text should be visible (got '%s')\n", field.Visible())
+	}
+
+	// wide character under cursor is visible
+	for i := 0; i < 3; i += 1 {
+		field.OnKey(goncurses.KEY_RIGHT)
+	}
+	if field.Visible() != "本語テ" || field.CursorColumn() != 4 {
+		t.Fatalf("Character under cursor should be visible (got '%s', cursor column %d)\n", field.Visible(), field.CursorColumn())
+	}
+
+	// wide character is not split
+	mixed := console.NewTextField("Titre:", "ab日c", 3)
+	mixed.OnKey(goncurses.KEY_HOME)
+	if mixed.Visible() != "ab " {
+		t.Fatalf("Wide character should not be split (got '%s')\n", mixed.Visible())
+	}
+}
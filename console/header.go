@@ -0,0 +1,67 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bookstore-go/download"
+	"github.com/bookstore-go/utils"
+)
+
+// screens named in breadcrumbs of header; other screens are not shown
+type CrumbScreen interface {
+	Crumb() string
+}
+
+// names of stacked screens, from first to top
+func (t *Terminal) Breadcrumbs() []string {
+	var crumbs []string
+	for _, ctx := range t.ScreenStack {
+		if cs, ok := ctx.CurrentScreen.(CrumbScreen); ok {
+			crumbs = append(crumbs, cs.Crumb())
+		}
+	}
+	return crumbs
+}
+
+// crumbs separated with '>'; first crumbs are replaced with "..." when text is wider than width
+func BreadcrumbText(crumbs []string, width int) string {
+	for i := range crumbs {
+		text := strings.Join(crumbs[i:], " > ")
+		if i > 0 {
+			text = "... > " + text
+		}
+		if StringWidth(text) <= width {
+			return text
+		}
+	}
+	if len(crumbs) == 0 {
+		return ""
+	}
+	// last crumb alone, cut if needed
+	return CutText(crumbs[len(crumbs)-1], width)
+}
+
+// first line of screen: path of screen from stack
+func (t *Terminal) DrawHeader() {
+	t.PrintStyle(0, 0, FitText(BreadcrumbText(t.Breadcrumbs(), t.Cols), t.Cols), StyleTitle)
+}
+
+// database and file stores state shown at right of status bar
+func ConnectionState() string {
+	state := "db:off"
+	if utils.DatabaseObj.Connected {
+		state = "db:on"
+	}
+	vendors := download.GetVendorsState()
+	if len(vendors) > 0 {
+		logged := 0
+		for _, v := range vendors {
+			if v.LoggedIn {
+				logged += 1
+			}
+		}
+		state += fmt.Sprintf(" stores:%d/%d", logged, len(vendors))
+	}
+	return state
+}
@@ -0,0 +1,86 @@
+package console_test
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/config"
+	"github.com/bookstore-go/console"
+	"github.com/bookstore-go/download"
+	"github.com/bookstore-go/utils"
+	"github.com/rthornton128/goncurses"
+)
+
+func TestBreadcrumbText(t *testing.T) {
+	crumbs := []string{"Menu", "Subjects", "Go (3 books)", "Learning Go"}
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{80, "Menu > Subjects > Go (3 books) > Learning Go"},
+		{40, "... > Go (3 books) > Learning Go"},
+		{20, "... > Learning Go"},
+		{8, "Learning"},
+	}
+	for _, test := range tests {
+		if text := console.BreadcrumbText(crumbs, test.width); text != test.want {
+			t.Fatalf("Breadcrumbs for width %d should be '%s' (got '%s')\n", test.width, test.want, text)
+		}
+	}
+	if text := console.BreadcrumbText(nil, 80); text != "" {
+		t.Fatalf("No breadcrumbs expected (got '%s')\n", text)
+	}
+}
+
+func TestHeaderAndState(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	books := &console.SubjectBooks{Sub: &utils.Subject{Id: 2, Name: "Go"}, SubjectIds: []uint{2}}
+	vs.SendKeys(goncurses.KEY_DOWN)
+	tty.NewScreen(&runScreen{Test: func(tty *console.Terminal) {
+		tty.NewScreen(books)
+	}})
+
+	shot := vs.LastShot()
+	if line := shotLine(shot, 0); line != "Go (3 books)" {
+		t.Fatalf("Header of books expected (got '%s')\n", line)
+	}
+	if line := shotLine(shot, 23); !strings.HasSuffix(line, " db:on") {
+		t.Fatalf("Database state expected at right of status bar (got '%s')\n", line)
+	}
+	if line := shotLine(shot, 23); !strings.HasPrefix(line, "?: help") {
+		t.Fatalf("Help expected at left of status bar (got '%s')\n", line)
+	}
+}
+
+func TestConnectionState(t *testing.T) {
+	get_config := config.GetConfig
+	t.Cleanup(func() {
+		// stores are unloaded once database is closed
+		download.InitVendorsData()
+		config.GetConfig = get_config
+	})
+	config.GetConfig = func() config.Config {
+		return config.Config{}
+	}
+	useFakeDb(t, fakeQuery{"FROM FILE_STORE", [][]driver.Value{
+		{int64(1), "Google", "GOOG", int64(0), ""},
+		{int64(2), "OneDrive", "MSOD", int64(0), ""},
+	}})
+	download.InitVendorsData()
+
+	if state := console.ConnectionState(); state != "db:on stores:0/2" {
+		t.Fatalf("No store should be logged in (got '%s')\n", state)
+	}
+	// token expires in an hour
+	err := download.OnToken("access_token=abc&token_type=Bearer&expires_in=3599", download.GetStorageData(1))
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if state := console.ConnectionState(); state != "db:on stores:1/2" {
+		t.Fatalf("Google store should be logged in (got '%s')\n", state)
+	}
+}
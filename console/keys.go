@@ -0,0 +1,418 @@
+package console
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rthornton128/goncurses"
+)
+
+// named command of screens; keys are bound to actions by the active keymap
+type Action string
+
+const (
+	ActionHelp      Action = "help"
+	ActionQuit      Action = "quit"
+	ActionCancel    Action = "cancel"
+	ActionSave      Action = "save"
+	ActionOpen      Action = "open"
+	ActionUp        Action = "up"
+	ActionDown      Action = "down"
+	ActionLeft      Action = "left"
+	ActionRight     Action = "right"
+	ActionPageUp    Action = "page-up"
+	ActionPageDown  Action = "page-down"
+	ActionHome      Action = "home"
+	ActionEnd       Action = "end"
+	ActionFilter    Action = "filter"
+	ActionNextMatch Action = "next-match"
+	ActionPrevMatch Action = "prev-match"
+	ActionExpand    Action = "expand"
+	ActionCollapse  Action = "collapse"
+	ActionCut       Action = "cut"
+	ActionPaste     Action = "paste"
+	ActionPasteRoot Action = "paste-root"
+	ActionDownload  Action = "download"
+	ActionEdit      Action = "edit"
+	ActionSubjects  Action = "subjects"
+	ActionLinks     Action = "links"
+	ActionToggle    Action = "toggle"
+	ActionAdd       Action = "add"
+	ActionDelete    Action = "delete"
+	ActionMerge     Action = "merge"
+	ActionNext      Action = "next"
+	ActionPrevious  Action = "previous"
+	ActionYes       Action = "yes"
+	ActionNo        Action = "no"
+)
+
+type ActionInfo struct {
+	Name Action
+	Keys []string
+	Help string
+}
+
+// actions with default keys and help text, in help overlay order
+var ActionTable = []ActionInfo{
+	{ActionHelp, []string{"?", "F1"}, "help"},
+	{ActionQuit, []string{"q", "ESC"}, "quit"},
+	{ActionCancel, []string{"ESC"}, "cancel"},
+	{ActionSave, []string{"F2"}, "save"},
+	{ActionOpen, []string{"RETURN", "ENTER"}, "open"},
+	{ActionUp, []string{"UP"}, "up"},
+	{ActionDown, []string{"DOWN"}, "down"},
+	{ActionLeft, []string{"LEFT"}, "left"},
+	{ActionRight, []string{"RIGHT"}, "right"},
+	{ActionPageUp, []string{"PGUP"}, "previous page"},
+	{ActionPageDown, []string{"PGDN"}, "next page"},
+	{ActionHome, []string{"HOME"}, "first"},
+	{ActionEnd, []string{"END"}, "last"},
+	{ActionFilter, []string{"/"}, "filter"},
+	{ActionNextMatch, []string{"n"}, "next match"},
+	{ActionPrevMatch, []string{"N"}, "previous match"},
+	{ActionExpand, []string{"RIGHT", "+"}, "expand"},
+	{ActionCollapse, []string{"LEFT", "-"}, "collapse"},
+	{ActionCut, []string{"x"}, "cut"},
+	{ActionPaste, []string{"p"}, "paste under"},
+	{ActionPasteRoot, []string{"P"}, "paste as root"},
+	{ActionDownload, []string{"d"}, "download"},
+	{ActionEdit, []string{"e"}, "edit"},
+	{ActionSubjects, []string{"s"}, "subjects"},
+	{ActionLinks, []string{"l"}, "files"},
+	{ActionToggle, []string{"SPACE"}, "assign/unassign, exclude"},
+	{ActionAdd, []string{"a"}, "add"},
+	{ActionDelete, []string{"x"}, "delete"},
+	{ActionMerge, []string{"m"}, "merge into book"},
+	{ActionNext, []string{"n"}, "next"},
+	{ActionPrevious, []string{"p"}, "previous"},
+	{ActionYes, []string{"y", "Y"}, "yes"},
+	{ActionNo, []string{"n", "N", "ESC"}, "no"},
+}
+
+// keys added to default bindings by presets
+var KeyPresets = map[string]map[Action][]string{
+	"default": {},
+	"vi": {
+		ActionDown:     {"j"},
+		ActionUp:       {"k"},
+		ActionHome:     {"g"},
+		ActionEnd:      {"G"},
+		ActionPageDown: {"C-f"},
+		ActionPageUp:   {"C-b"},
+	},
+	"emacs": {
+		ActionDown:     {"C-n"},
+		ActionUp:       {"C-p"},
+		ActionRight:    {"C-f"},
+		ActionLeft:     {"C-b"},
+		ActionPageDown: {"C-v"},
+		ActionHome:     {"C-a"},
+		ActionEnd:      {"C-e"},
+		ActionQuit:     {"C-g"},
+		ActionCancel:   {"C-g"},
+	},
+}
+
+var keyNames = map[string]goncurses.Key{
+	"ESC":       goncurses.KEY_ESC,
+	"RETURN":    goncurses.KEY_RETURN,
+	"ENTER":     goncurses.KEY_ENTER,
+	"TAB":       goncurses.KEY_TAB,
+	"BTAB":      goncurses.KEY_BTAB,
+	"SPACE":     ' ',
+	"BACKSPACE": goncurses.KEY_BACKSPACE,
+	"DEL":       goncurses.KEY_DC,
+	"INS":       goncurses.KEY_IC,
+	"UP":        goncurses.KEY_UP,
+	"DOWN":      goncurses.KEY_DOWN,
+	"LEFT":      goncurses.KEY_LEFT,
+	"RIGHT":     goncurses.KEY_RIGHT,
+	"PGUP":      goncurses.KEY_PAGEUP,
+	"PGDN":      goncurses.KEY_PAGEDOWN,
+	"HOME":      goncurses.KEY_HOME,
+	"END":       goncurses.KEY_END,
+}
+
+// key from name: a character, C-x for control keys, F1..F12 or a name of keyNames (case insensitive)
+func ParseKey(name string) (goncurses.Key, error) {
+	if r := []rune(name); len(r) == 1 {
+		return goncurses.Key(r[0]), nil
+	}
+	upper := strings.ToUpper(name)
+	if key, ok := keyNames[upper]; ok {
+		return key, nil
+	}
+	if len(name) == 3 && upper[:2] == "C-" && upper[2] >= 'A' && upper[2] <= 'Z' {
+		return goncurses.Key(upper[2] & 0x1f), nil
+	}
+	if strings.HasPrefix(upper, "F") {
+		n, err := strconv.Atoi(upper[1:])
+		if err == nil && n >= 1 && n <= 12 {
+			return goncurses.KEY_F1 + goncurses.Key(n-1), nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown key '%s'", name)
+}
+
+// name of key as parsed by ParseKey
+func KeyName(key goncurses.Key) string {
+	for name, k := range keyNames {
+		if k == key {
+			return name
+		}
+	}
+	switch {
+	case key > 0 && key < 32:
+		return "C-" + string(rune(key+'a'-1))
+	case key >= goncurses.KEY_F1 && key <= goncurses.KEY_F12:
+		return fmt.Sprintf("F%d", key-goncurses.KEY_F1+1)
+	case key > 32 && key < 127:
+		return string(rune(key))
+	}
+	return strconv.Itoa(int(key))
+}
+
+// keys bound to actions; a key may be bound to actions of different screens
+type Keymap struct {
+	Bindings map[Action][]goncurses.Key
+}
+
+// active keymap, read by screens and widgets
+var Keys = DefaultKeymap()
+
+func DefaultKeymap() *Keymap {
+	km, _ := NewKeymap("default")
+	return km
+}
+
+// default bindings with keys of preset
+func NewKeymap(preset string) (*Keymap, error) {
+	extra, ok := KeyPresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("Unknown key preset '%s'", preset)
+	}
+	km := &Keymap{Bindings: make(map[Action][]goncurses.Key)}
+	for _, info := range ActionTable {
+		names := append(append([]string{}, info.Keys...), extra[info.Name]...)
+		err := km.BindNames(info.Name, names)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return km, nil
+}
+
+// keymap of [keys] configuration section: preset = "vi" or "emacs", and action = "key" or ["key", ...]
+// replacing keys of action
+func LoadKeymap(conf map[string]interface{}) (*Keymap, error) {
+	preset := "default"
+	if value, ok := conf["preset"]; ok {
+		preset = fmt.Sprint(value)
+	}
+	km, err := NewKeymap(preset)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range conf {
+		if name == "preset" {
+			continue
+		}
+		action := Action(name)
+		if _, ok := km.Bindings[action]; !ok {
+			return nil, fmt.Errorf("Unknown action '%s'", name)
+		}
+		var names []string
+		switch value := value.(type) {
+		case string:
+			names = []string{value}
+		case []interface{}:
+			for _, v := range value {
+				names = append(names, fmt.Sprint(v))
+			}
+		default:
+			return nil, fmt.Errorf("Invalid keys of action '%s'", name)
+		}
+		km.Bindings[action] = nil
+		err = km.BindNames(action, names)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return km, nil
+}
+
+// sets active keymap from [keys] configuration section
+func LoadKeys(conf map[string]interface{}) error {
+	km, err := LoadKeymap(conf)
+	if err != nil {
+		return err
+	}
+	Keys = km
+	return nil
+}
+
+func (km *Keymap) BindNames(action Action, names []string) error {
+	for _, name := range names {
+		key, err := ParseKey(name)
+		if err != nil {
+			return fmt.Errorf("Action '%s': %v", action, err)
+		}
+		km.Bind(action, key)
+	}
+	return nil
+}
+
+func (km *Keymap) Bind(action Action, keys ...goncurses.Key) {
+	for _, key := range keys {
+		if !km.Is(key, action) {
+			km.Bindings[action] = append(km.Bindings[action], key)
+		}
+	}
+}
+
+func (km *Keymap) Is(key goncurses.Key, action Action) bool {
+	for _, k := range km.Bindings[action] {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// first of actions bound to key, empty if none
+func (km *Keymap) Action(key goncurses.Key, actions ...Action) Action {
+	for _, action := range actions {
+		if km.Is(key, action) {
+			return action
+		}
+	}
+	return ""
+}
+
+// keys of action separated with '/'
+func (km *Keymap) Names(action Action) string {
+	var names []string
+	for _, key := range km.Bindings[action] {
+		names = append(names, KeyName(key))
+	}
+	return strings.Join(names, "/")
+}
+
+func HelpOf(action Action) string {
+	for _, info := range ActionTable {
+		if info.Name == action {
+			return info.Help
+		}
+	}
+	return string(action)
+}
+
+// status bar help: first key of each bound action
+func (km *Keymap) Hints(actions ...Action) string {
+	var hints []string
+	for _, action := range actions {
+		if keys := km.Bindings[action]; len(keys) > 0 {
+			hints = append(hints, KeyName(keys[0])+": "+HelpOf(action))
+		}
+	}
+	return strings.Join(hints, "   ")
+}
+
+// help overlay lines: keys and help of bound actions
+func (km *Keymap) HelpLines(actions []Action) []string {
+	width := 0
+	for _, action := range actions {
+		if w := len(km.Names(action)); w > width {
+			width = w
+		}
+	}
+	var lines []string
+	for _, action := range actions {
+		if len(km.Bindings[action]) > 0 {
+			lines = append(lines, fmt.Sprintf("%-*s  %s", width, km.Names(action), HelpOf(action)))
+		}
+	}
+	return lines
+}
+
+// ----------- HELP OVERLAY -----------
+
+// screens listing their actions in help overlay, help action excepted; other screens list every action
+type HelpScreen interface {
+	HelpActions() []Action
+}
+
+// screens reading text: printable keys are typed, not read as actions
+type TypingScreen interface {
+	Typing() bool
+}
+
+func ScreenActions(scr Screen) []Action {
+	if hs, ok := scr.(HelpScreen); ok {
+		return append([]Action{ActionHelp}, hs.HelpActions()...)
+	}
+	var actions []Action
+	for _, info := range ActionTable {
+		actions = append(actions, info.Name)
+	}
+	return actions
+}
+
+// key opens help overlay on screen
+func IsHelpKey(scr Screen, key goncurses.Key) bool {
+	if !Keys.Is(key, ActionHelp) {
+		return false
+	}
+	if ts, ok := scr.(TypingScreen); ok && ts.Typing() {
+		return key < 32 || key > 255
+	}
+	return true
+}
+
+// box with keys of actions drawn over screen until a key is pressed
+func (t *Terminal) ShowHelp(actions []Action) goncurses.Key {
+	lines := Keys.HelpLines(actions)
+	for {
+		t.drawHelp(lines)
+		key := t.GetChar()
+		if key == goncurses.KEY_MOUSE {
+			// click closes help
+			t.Backend.GetMouse()
+		}
+		if key != goncurses.KEY_RESIZE {
+			return key
+		}
+	}
+}
+
+func (t *Terminal) drawHelp(lines []string) {
+	title := " Keys (any key to close) "
+	width := StringWidth(title)
+	for _, line := range lines {
+		if w := StringWidth(line); w > width {
+			width = w
+		}
+	}
+	if width > t.Cols-4 {
+		width = t.Cols - 4
+	}
+	if width < 1 {
+		return
+	}
+	height := len(lines)
+	if height > t.Lines-3 {
+		height = t.Lines - 3
+	}
+	top := (t.Lines - height - 2) / 2
+	left := (t.Cols - width - 4) / 2
+
+	border := "+" + strings.Repeat("-", width+2) + "+"
+	t.PrintAt(top, left, border)
+	t.PrintStyle(top, left+2, CutText(title, width), StyleTitle)
+	for i := 0; i < height; i += 1 {
+		t.PrintAt(top+1+i, left, "| "+FitText(lines[i], width)+" |")
+	}
+	t.PrintAt(top+1+height, left, border)
+}
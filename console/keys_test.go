@@ -0,0 +1,93 @@
+package console_test
+
+import (
+	"testing"
+
+	"github.com/bookstore-go/console"
+	"github.com/rthornton128/goncurses"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  goncurses.Key
+	}{
+		{"q", 'q'},
+		{"?", '?'},
+		{"ESC", goncurses.KEY_ESC},
+		{"pgdn", goncurses.KEY_PAGEDOWN},
+		{"SPACE", ' '},
+		{"C-n", 14},
+		{"F2", goncurses.KEY_F2},
+		{"F12", goncurses.KEY_F12},
+	}
+	for _, test := range tests {
+		key, err := console.ParseKey(test.name)
+		if err != nil || key != test.key {
+			t.Fatalf("Key '%s' should be %d (got %d, %v)\n", test.name, test.key, key, err)
+		}
+		if _, err := console.ParseKey(console.KeyName(key)); err != nil {
+			t.Fatalf("Name of key '%s' should parse (got %v)\n", test.name, err)
+		}
+	}
+	if name := console.KeyName(14); name != "C-n" {
+		t.Fatalf("Name of control key should be C-n (got %s)\n", name)
+	}
+
+	for _, name := range []string{"", "F13", "C-1", "foo"} {
+		if _, err := console.ParseKey(name); err == nil {
+			t.Fatalf("Key '%s' should not parse\n", name)
+		}
+	}
+}
+
+func TestKeymap(t *testing.T) {
+	km := console.DefaultKeymap()
+	if !km.Is('q', console.ActionQuit) || !km.Is(goncurses.KEY_ESC, console.ActionQuit) || km.Is('j', console.ActionDown) {
+		t.Fatalf("Default bindings expected (got %v)\n", km.Bindings)
+	}
+	// first action of candidates bound to key
+	if action := km.Action('x', console.ActionDelete, console.ActionCut); action != console.ActionDelete {
+		t.Fatalf("Delete action expected (got '%s')\n", action)
+	}
+	if action := km.Action('z', console.ActionDelete, console.ActionCut); action != "" {
+		t.Fatalf("No action expected (got '%s')\n", action)
+	}
+	if hints := km.Hints(console.ActionHelp, console.ActionQuit); hints != "?: help   q: quit" {
+		t.Fatalf("Hints mismatch (got '%s')\n", hints)
+	}
+
+	km, err := console.LoadKeymap(map[string]interface{}{
+		"preset":   "vi",
+		"quit":     "Q",
+		"download": []interface{}{"D", "F5"},
+		"edit":     []interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("Keymap should load (got %v)\n", err)
+	}
+	if !km.Is('j', console.ActionDown) || !km.Is(goncurses.KEY_DOWN, console.ActionDown) || !km.Is('G', console.ActionEnd) {
+		t.Fatalf("vi keys should be added to default keys (got %v)\n", km.Bindings)
+	}
+	if km.Is('q', console.ActionQuit) || !km.Is('Q', console.ActionQuit) {
+		t.Fatalf("Quit should be bound to Q only (got %s)\n", km.Names(console.ActionQuit))
+	}
+	if names := km.Names(console.ActionDownload); names != "D/F5" {
+		t.Fatalf("Download keys mismatch (got %s)\n", names)
+	}
+	if lines := km.HelpLines([]console.Action{console.ActionEdit, console.ActionQuit}); len(lines) != 1 || lines[0] != "Q  quit" {
+		t.Fatalf("Unbound actions should not be listed (got %q)\n", lines)
+	}
+
+	bad := []map[string]interface{}{
+		{"preset": "nano"},
+		{"fly": "f"},
+		{"quit": "F99"},
+		{"quit": 3},
+	}
+	for _, conf := range bad {
+		if _, err := console.LoadKeymap(conf); err == nil {
+			t.Fatalf("Keymap %v should not load\n", conf)
+		}
+	}
+}
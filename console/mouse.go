@@ -0,0 +1,123 @@
+package console
+
+import (
+	"github.com/rthornton128/goncurses"
+)
+
+type MouseAction int
+
+const (
+	MouseClick MouseAction = iota + 1
+	MouseDoubleClick
+	MouseWheelUp
+	MouseWheelDown
+)
+
+// mouse event read after KEY_MOUSE; screens get logic coordinates of their context
+type MouseEvent struct {
+	Action MouseAction
+	Line   int
+	Col    int
+}
+
+// wheel down, not defined by goncurses: button 5 follows button 4 (5 bits per button with ncurses 6)
+const M_B5_PRESSED = goncurses.M_B4_PRESSED << 5
+
+// mouse events reported by curses
+const MouseEvents = goncurses.M_B1_CLICKED | goncurses.M_B1_DBL_CLICKED | goncurses.M_B4_PRESSED | M_B5_PRESSED
+
+// lines scrolled by a wheel step
+const WheelLines = 3
+
+// screens handling mouse events; wheel scrolls other screens with ScrollScr
+type MouseScreen interface {
+	OnMouse(ev MouseEvent)
+}
+
+// logic line and column of context at terminal line and column
+func (ctx *ScreenContext) LogicPos(line, col int) (int, int) {
+	return (line + ctx.Scroll) / ctx.LineSize, col / ctx.ColSize
+}
+
+// reads mouse event after KEY_MOUSE and sends it to screen on top
+func (t *Terminal) ReadMouse() {
+	ev, ok := t.Backend.GetMouse()
+	ctx := t.CurrentContext()
+	if !ok || ctx == nil {
+		return
+	}
+	ev.Line, ev.Col = ctx.LogicPos(ev.Line, ev.Col)
+
+	if ms, ok := ctx.CurrentScreen.(MouseScreen); ok {
+		ms.OnMouse(ev)
+	} else if ev.Action == MouseWheelUp {
+		t.ScrollScr(-WheelLines)
+	} else if ev.Action == MouseWheelDown {
+		t.ScrollScr(WheelLines)
+	}
+}
+
+// ----------- WIDGETS -----------
+
+// item at line of list drawn at top, -1 if none
+func (l *ListView) IndexAt(line int) int {
+	index := l.Top + line
+	if line < 0 || line >= l.Height || index >= l.Count {
+		return -1
+	}
+	return index
+}
+
+// click selects item of list drawn at line, col, wheel moves selection.
+// Returns true if an item is double-clicked
+func (l *ListView) OnMouse(ev MouseEvent, line, col int) bool {
+	switch ev.Action {
+	case MouseWheelUp:
+		l.Select(l.Selected - WheelLines)
+	case MouseWheelDown:
+		l.Select(l.Selected + WheelLines)
+	case MouseClick, MouseDoubleClick:
+		index := l.IndexAt(ev.Line - line)
+		if index < 0 || ev.Col < col || ev.Col >= col+l.Width {
+			return false
+		}
+		l.Select(index)
+		return ev.Action == MouseDoubleClick
+	}
+	return false
+}
+
+// click selects item of grid drawn at line, col, wheel moves selection.
+// Returns true if an item is double-clicked
+func (g *GridView) OnMouse(ev MouseEvent, line, col int) bool {
+	switch ev.Action {
+	case MouseWheelUp:
+		g.Select(g.Selected - WheelLines)
+	case MouseWheelDown:
+		g.Select(g.Selected + WheelLines)
+	case MouseClick, MouseDoubleClick:
+		if ev.Line < line || ev.Col < col {
+			return false
+		}
+		index := g.IndexAt(ev.Line-line, (ev.Col-col)/g.ColWidth)
+		if index < 0 {
+			return false
+		}
+		g.Select(index)
+		return ev.Action == MouseDoubleClick
+	}
+	return false
+}
+
+// wheel scrolls text; returns false for other events
+func (v *TextViewer) OnMouse(ev MouseEvent) bool {
+	switch ev.Action {
+	case MouseWheelUp:
+		v.ScrollTo(v.Top - WheelLines)
+	case MouseWheelDown:
+		v.ScrollTo(v.Top + WheelLines)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package console_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bookstore-go/console"
+)
+
+func TestListViewMouse(t *testing.T) {
+	list := console.NewListView(20, 5, 30, func(index int) string {
+		return fmt.Sprintf("item %d", index)
+	})
+	list.Select(12)
+
+	// list drawn at line 2, column 4: visible items are 8 to 12
+	if list.OnMouse(console.MouseEvent{Action: console.MouseClick, Line: 3, Col: 10}, 2, 4) || list.Selected != 9 {
+		t.Fatalf("Click should select item 9 (got %d)\n", list.Selected)
+	}
+	if !list.OnMouse(console.MouseEvent{Action: console.MouseDoubleClick, Line: 6, Col: 4}, 2, 4) || list.Selected != 12 {
+		t.Fatalf("Double-click should select item 12 (got %d)\n", list.Selected)
+	}
+	for _, ev := range []console.MouseEvent{
+		{Action: console.MouseDoubleClick, Line: 7, Col: 10},
+		{Action: console.MouseDoubleClick, Line: 1, Col: 10},
+		{Action: console.MouseDoubleClick, Line: 3, Col: 34},
+	} {
+		if list.OnMouse(ev, 2, 4) || list.Selected != 12 {
+			t.Fatalf("Click %v outside list should be ignored (got %d)\n", ev, list.Selected)
+		}
+	}
+
+	list.OnMouse(console.MouseEvent{Action: console.MouseWheelDown}, 2, 4)
+	if list.Selected != 15 {
+		t.Fatalf("Wheel should move selection 3 items (got %d)\n", list.Selected)
+	}
+}
+
+func TestGridViewMouse(t *testing.T) {
+	// 10 items in 4 rows of 3 columns
+	grid := console.NewGridView(10, 4, 30, 10, func(index int) string {
+		return fmt.Sprintf("item %d", index)
+	})
+	if !grid.OnMouse(console.MouseEvent{Action: console.MouseDoubleClick, Line: 3, Col: 15}, 2, 0) || grid.Selected != 5 {
+		t.Fatalf("Item 5 should be double-clicked (got %d)\n", grid.Selected)
+	}
+	if grid.OnMouse(console.MouseEvent{Action: console.MouseClick, Line: 5, Col: 25}, 2, 0) || grid.Selected != 5 {
+		t.Fatalf("Click after last item should be ignored (got %d)\n", grid.Selected)
+	}
+}
+
+func TestLogicPos(t *testing.T) {
+	ctx := &console.ScreenContext{LineSize: 2, ColSize: 1, Scroll: 4}
+	if line, col := ctx.LogicPos(3, 7); line != 3 || col != 7 {
+		t.Fatalf("Logic position should be 3,7 (got %d,%d)\n", line, col)
+	}
+}
+
+func TestSubjectsScreenMouse(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	// tree starts on line 1, below header
+	vs.SendMouse(console.MouseClick, 3, 5)
+	vs.SendMouse(console.MouseDoubleClick, 2, 5)
+	subscr := &console.SubjectsScreen{}
+	tty.NewScreen(subscr)
+
+	if len(vs.Shots) != 3 {
+		t.Fatalf("3 reads expected (got %d)\n", len(vs.Shots))
+	}
+	// books of Programming
+	if line := shotLine(vs.Shots[2], 0); line != "Subjects > Programming (3 books)" {
+		t.Fatalf("Books of Programming expected (got '%s')\n", line)
+	}
+	if len(vs.Mouse) != 0 || subscr.List.Selected != 1 {
+		t.Fatalf("Programming should be selected (got %d)\n", subscr.List.Selected)
+	}
+}
@@ -0,0 +1,394 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/rthornton128/goncurses"
+	"golang.org/x/net/html"
+)
+
+// ncurses A_ITALIC, not defined by goncurses
+const A_ITALIC goncurses.Char = 1 << 31
+
+// text drawn with curses attributes
+type Span struct {
+	Text string
+	Attr goncurses.Char
+}
+
+type RichLine []Span
+
+func (line RichLine) Text() string {
+	var sb strings.Builder
+	for _, span := range line {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+// columns of rune on terminal: 2 for east asian wide characters and emojis, 0 for combining marks
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r):
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+func StringWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// ----------- HTML RENDERER -----------
+
+type listState struct {
+	Ordered bool
+	Count   int
+}
+
+// html laid out in lines of width columns
+type htmlRenderer struct {
+	Width  int
+	Lines  []RichLine
+	Line   RichLine
+	Column int
+	// word being read: spans of text without space
+	Word      RichLine
+	WordWidth int
+	Space     bool
+	// prefix of first line of list item, indent of following lines
+	Prefix string
+	Indent int
+	Lists  []listState
+	Bold   int
+	Italic int
+	Link   string
+	Links  []string
+	Pre    int
+	Skip   int
+}
+
+// renders html description in lines of width columns: paragraphs, lists, headings,
+// bold/italic, links (numbered, urls listed at end) and entities. Plain text is rendered as html
+func RenderHtml(text string, width int) []RichLine {
+	if width < 1 {
+		width = 1
+	}
+	r := &htmlRenderer{Width: width}
+	z := html.NewTokenizer(strings.NewReader(text))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		token := z.Token()
+		switch tt {
+		case html.TextToken:
+			if r.Skip == 0 {
+				r.text(token.Data)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			r.startTag(token)
+		case html.EndTagToken:
+			r.endTag(token)
+		}
+	}
+	r.endLine()
+
+	if len(r.Links) > 0 {
+		r.block()
+		for i, link := range r.Links {
+			r.text(fmt.Sprintf("[%d] %s", i+1, link))
+			r.endLine()
+		}
+	}
+
+	// no trailing empty lines
+	for len(r.Lines) > 0 && len(r.Lines[len(r.Lines)-1]) == 0 {
+		r.Lines = r.Lines[:len(r.Lines)-1]
+	}
+	return r.Lines
+}
+
+func (r *htmlRenderer) attr() goncurses.Char {
+	attr := goncurses.Char(goncurses.A_NORMAL)
+	if r.Bold > 0 {
+		attr |= goncurses.A_BOLD
+	}
+	if r.Italic > 0 {
+		attr |= A_ITALIC
+	}
+	if len(r.Link) > 0 {
+		attr |= goncurses.A_UNDERLINE
+	}
+	return attr
+}
+
+func (r *htmlRenderer) startTag(token html.Token) {
+	switch token.Data {
+	case "script", "style":
+		r.Skip += 1
+	case "br":
+		r.lineBreak()
+	case "p", "div", "blockquote", "table", "tr":
+		r.block()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.block()
+		r.Bold += 1
+	case "pre":
+		r.block()
+		r.Pre += 1
+	case "b", "strong":
+		r.Bold += 1
+	case "i", "em", "cite":
+		r.Italic += 1
+	case "a":
+		r.Link = ""
+		for _, a := range token.Attr {
+			if a.Key == "href" {
+				r.Link = a.Val
+			}
+		}
+	case "ul", "ol":
+		if len(r.Lists) == 0 {
+			r.block()
+		} else {
+			r.endLine()
+		}
+		r.Lists = append(r.Lists, listState{Ordered: token.Data == "ol"})
+	case "li":
+		r.endLine()
+		depth := len(r.Lists)
+		if depth == 0 {
+			r.Lists = append(r.Lists, listState{})
+			depth = 1
+		}
+		list := &r.Lists[depth-1]
+		list.Count += 1
+		bullet := "• "
+		if list.Ordered {
+			bullet = fmt.Sprintf("%d. ", list.Count)
+		}
+		r.Prefix = strings.Repeat("  ", depth-1) + bullet
+		r.Indent = StringWidth(r.Prefix)
+	}
+}
+
+// counter of open tags; end tags without start tag are ignored
+func closeTag(count *int) {
+	if *count > 0 {
+		*count -= 1
+	}
+}
+
+func (r *htmlRenderer) endTag(token html.Token) {
+	switch token.Data {
+	case "script", "style":
+		closeTag(&r.Skip)
+	case "p", "div", "blockquote", "table":
+		r.block()
+	case "tr":
+		r.endLine()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		closeTag(&r.Bold)
+		r.block()
+	case "pre":
+		closeTag(&r.Pre)
+		r.block()
+	case "b", "strong":
+		closeTag(&r.Bold)
+	case "i", "em", "cite":
+		closeTag(&r.Italic)
+	case "a":
+		r.endLink()
+	case "ul", "ol":
+		r.endLine()
+		if len(r.Lists) > 0 {
+			r.Lists = r.Lists[:len(r.Lists)-1]
+		}
+		r.Prefix = ""
+		r.Indent = 2 * len(r.Lists)
+		if len(r.Lists) == 0 {
+			r.block()
+		}
+	case "li":
+		r.endLine()
+	}
+}
+
+// link is numbered after its text unless text is the url
+func (r *htmlRenderer) endLink() {
+	link := r.Link
+	r.Link = ""
+	if len(link) == 0 || strings.HasPrefix(link, "#") || strings.HasPrefix(link, "javascript:") {
+		return
+	}
+	if strings.TrimSpace(r.Word.Text()) == link {
+		return
+	}
+	r.Links = append(r.Links, link)
+	r.addText(fmt.Sprintf("[%d]", len(r.Links)), goncurses.A_NORMAL)
+}
+
+func (r *htmlRenderer) text(text string) {
+	attr := r.attr()
+	if r.Pre > 0 {
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				r.endLine()
+			}
+			r.addText(strings.ReplaceAll(line, "\t", "    "), attr)
+			r.endWord()
+		}
+		return
+	}
+	for _, c := range text {
+		if c == '\u00a0' {
+			// no break space
+			r.addText(" ", attr)
+		} else if unicode.IsSpace(c) {
+			r.endWord()
+			r.Space = true
+		} else {
+			r.addText(string(c), attr)
+		}
+	}
+}
+
+// appends text to current word
+func (r *htmlRenderer) addText(text string, attr goncurses.Char) {
+	if n := len(r.Word); n > 0 && r.Word[n-1].Attr == attr {
+		r.Word[n-1].Text += text
+	} else {
+		r.Word = append(r.Word, Span{text, attr})
+	}
+	r.WordWidth += StringWidth(text)
+}
+
+// word is put on current line, or next one if it does not fit; words wider than a line are cut
+func (r *htmlRenderer) endWord() {
+	if len(r.Word) == 0 {
+		return
+	}
+	word := r.Word
+	need := r.WordWidth
+	r.Word = nil
+	r.WordWidth = 0
+
+	space := r.Space && r.Column > r.lineStart()
+	r.Space = false
+	if space {
+		need += 1
+	}
+	if r.Column+need > r.Width && r.Column > r.lineStart() {
+		r.endLine()
+		space = false
+	}
+	if space {
+		r.put(" ", goncurses.A_NORMAL)
+	}
+	for _, span := range word {
+		r.putCut(span.Text, span.Attr)
+	}
+}
+
+// puts text, cut at end of lines
+func (r *htmlRenderer) putCut(text string, attr goncurses.Char) {
+	start := 0
+	r.startLine()
+	col := r.Column
+	for i, c := range text {
+		w := RuneWidth(c)
+		if col+w > r.Width && col > r.lineStart() {
+			r.put(text[start:i], attr)
+			r.endLine()
+			r.startLine()
+			start = i
+			col = r.Column
+		}
+		col += w
+	}
+	r.put(text[start:], attr)
+}
+
+// first column of text on current line
+func (r *htmlRenderer) lineStart() int {
+	if len(r.Line) == 0 {
+		return 0
+	}
+	return r.Indent
+}
+
+// list prefix or indent at start of line
+func (r *htmlRenderer) startLine() {
+	if len(r.Line) > 0 {
+		return
+	}
+	prefix := r.Prefix
+	if len(prefix) == 0 {
+		prefix = strings.Repeat(" ", r.Indent)
+	}
+	r.Prefix = ""
+	if len(prefix) > 0 {
+		r.Line = append(r.Line, Span{prefix, goncurses.A_NORMAL})
+		r.Column = StringWidth(prefix)
+	}
+}
+
+func (r *htmlRenderer) put(text string, attr goncurses.Char) {
+	if len(text) == 0 {
+		return
+	}
+	r.startLine()
+	if n := len(r.Line); n > 0 && r.Line[n-1].Attr == attr {
+		r.Line[n-1].Text += text
+	} else {
+		r.Line = append(r.Line, Span{text, attr})
+	}
+	r.Column += StringWidth(text)
+}
+
+// ends current line if not empty
+func (r *htmlRenderer) endLine() {
+	r.endWord()
+	if len(r.Line) > 0 {
+		r.Lines = append(r.Lines, r.Line)
+	}
+	r.Line = nil
+	r.Column = 0
+	r.Space = false
+}
+
+// <br>: ends line, empty line if line is empty (<br><br> is a blank line)
+func (r *htmlRenderer) lineBreak() {
+	r.endWord()
+	if len(r.Line) == 0 && len(r.Lines) > 0 {
+		r.Lines = append(r.Lines, RichLine{})
+	}
+	r.endLine()
+}
+
+// ends line and separates blocks with one empty line
+func (r *htmlRenderer) block() {
+	r.endLine()
+	if n := len(r.Lines); n > 0 && len(r.Lines[n-1]) > 0 {
+		r.Lines = append(r.Lines, RichLine{})
+	}
+}
@@ -0,0 +1,103 @@
+package console_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/console"
+	"github.com/rthornton128/goncurses"
+)
+
+func TestRenderHtml(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		width int
+		want  []string
+	}{
+		{"wrap", "The quick brown fox jumps over the lazy dog", 20, []string{"The quick brown fox", "jumps over the lazy", "dog"}},
+		{"spaces", "  one\n\ttwo   three ", 20, []string{"one two three"}},
+		{"paragraphs", "<p>One</p><p>Two</p>", 20, []string{"One", "", "Two"}},
+		{"break", "first<br>second<BR/>third", 20, []string{"first", "second", "third"}},
+		{"entities", "Tom &amp; Jerry&nbsp;&eacute;t&eacute; &lt;3", 30, []string{"Tom & Jerry été <3"}},
+		{"list", "<ul><li>first item that wraps here</li><li>second</li></ul>", 20, []string{"• first item that", "  wraps here", "• second"}},
+		{"ordered list", "Steps:<ol><li>read</li><li>write</li></ol>done", 20, []string{"Steps:", "", "1. read", "2. write", "", "done"}},
+		{"nested list", "<ul><li>a<ul><li>b</li></ul></li><li>c</li></ul>", 20, []string{"• a", "  • b", "• c"}},
+		{"link", `See <a href="http://golang.org">site</a>.`, 30, []string{"See site[1].", "", "[1] http://golang.org"}},
+		{"url link", `<a href="http://golang.org">http://golang.org</a>`, 30, []string{"http://golang.org"}},
+		{"heading", "<h2>Title</h2>Text", 20, []string{"Title", "", "Text"}},
+		{"wide characters", "日本語のテキスト", 10, []string{"日本語のテ", "キスト"}},
+		{"long word", "abcdefghijklmnopqrstuvwxyz", 10, []string{"abcdefghij", "klmnopqrst", "uvwxyz"}},
+		{"glued tags", "<b>bold</b>, normal", 20, []string{"bold, normal"}},
+		{"script", "<script>alert(1)</script><style>p {}</style>text", 20, []string{"text"}},
+		{"pre", "<pre>a  b\n  c</pre>", 20, []string{"a  b", "  c"}},
+		{"blank line", "first<br><br>second", 20, []string{"first", "", "second"}},
+		{"stray script end", "one</script> two</style><p>three</p>", 20, []string{"one two", "", "three"}},
+		{"stray end tags", "</b></i>one<b>two</b>", 20, []string{"onetwo"}},
+	}
+
+	for _, test := range tests {
+		var got []string
+		for _, line := range console.RenderHtml(test.html, test.width) {
+			got = append(got, line.Text())
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Fatalf("%s: lines mismatch (want %q, got %q)\n", test.name, test.want, got)
+		}
+	}
+}
+
+func TestRenderHtmlAttributes(t *testing.T) {
+	lines := console.RenderHtml(`<b>bold</b> <i>italic</i> <a href="x.html">link</a>`, 40)
+	if len(lines) != 3 {
+		t.Fatalf("3 lines expected (got %d)\n", len(lines))
+	}
+
+	want := console.RichLine{
+		{Text: "bold", Attr: goncurses.A_BOLD},
+		{Text: " ", Attr: goncurses.A_NORMAL},
+		{Text: "italic", Attr: console.A_ITALIC},
+		{Text: " ", Attr: goncurses.A_NORMAL},
+		{Text: "link", Attr: goncurses.A_UNDERLINE},
+		{Text: "[1]", Attr: goncurses.A_NORMAL},
+	}
+	if len(lines[0]) != len(want) {
+		t.Fatalf("Spans mismatch (want %v, got %v)\n", want, lines[0])
+	}
+	for i, span := range lines[0] {
+		if span != want[i] {
+			t.Fatalf("Span %d mismatch (want %v, got %v)\n", i, want[i], span)
+		}
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	if w := console.StringWidth("été"); w != 3 {
+		t.Fatalf("Width of 'été' should be 3 (got %d)\n", w)
+	}
+	if w := console.StringWidth("日本"); w != 4 {
+		t.Fatalf("Width of '日本' should be 4 (got %d)\n", w)
+	}
+	if cut := console.CutText("日本語", 5); cut != "日本" {
+		t.Fatalf("Wide character should not be split (got '%s')\n", cut)
+	}
+}
+
+func TestRenderHtmlUnbalanced(t *testing.T) {
+	// stray end tags do not cancel next start tags
+	lines := console.RenderHtml("</b></i></b>plain <b>bold</b> <i>italic</i>", 40)
+	want := console.RichLine{
+		{Text: "plain ", Attr: goncurses.A_NORMAL},
+		{Text: "bold", Attr: goncurses.A_BOLD},
+		{Text: " ", Attr: goncurses.A_NORMAL},
+		{Text: "italic", Attr: console.A_ITALIC},
+	}
+	if len(lines) != 1 || len(lines[0]) != len(want) {
+		t.Fatalf("Spans mismatch (want %v, got %v)\n", want, lines)
+	}
+	for i, span := range lines[0] {
+		if span != want[i] {
+			t.Fatalf("Span %d mismatch (want %v, got %v)\n", i, want[i], span)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package console
 
 import (
+	"fmt"
+	"image"
+	"strconv"
 	"strings"
 
 	"github.com/bookstore-go/download"
@@ -16,48 +19,64 @@ type Screen interface {
 	OnRefresh(Lines, Cols int)
 }
 
-type MenuScreen struct {
-	Tty              *Terminal
-	CursorX, CursorY int
+type MenuItem struct {
+	Key   goncurses.Key
+	Label string
 }
 
-func (menu *MenuScreen) PrintMenu() {
+type MenuScreen struct {
+	Tty    *Terminal
+	Items  []MenuItem
+	List   *ListView
+	Status *StatusBar
+}
 
-	Title := `
+const MenuTitle = `
     __________               __      _________ __                        
     \______   \ ____   ____ |  | __ /   _____//  |_  ___________   ____  
      |    |  _//  _ \ /  _ \|  |/ / \_____  \\   __\/  _ \_  __ \_/ __ \ 
      |    |   (  <_> |  <_> )    <  /        \|  | (  <_> )  | \/\  ___/ 
      |______  /\____/ \____/|__|_ \/_______  /|__|  \____/|__|    \___  >
             \/                   \/        \/                         \/ 
-	`
-
-	line1 := "         ------------------ Menu ------------------"
-	line2 := "                  a - Display subjects"
-	line3 := "                  b - Search books by subjects"
-	line4 := "                  c - Get book info"
-	line5 := "                  d - Download book"
-	line6 := "                  q - Quit"
-
-	menu.Tty.Println(Title)
-	menu.Tty.Println(line1)
-	menu.Tty.Println("")
-	menu.Tty.Println(line2)
-	menu.Tty.Println(line3)
-	menu.Tty.Println(line4)
-	menu.Tty.Println(line5)
-	menu.Tty.Println(line6)
-	menu.Tty.Printf("> ")
+`
+
+// first line of menu items, below title
+const MenuFirstLine = 10
+
+func (menu *MenuScreen) PrintMenu() {
+
+	for i, line := range strings.Split(MenuTitle, "\n") {
+		menu.Tty.PrintStyle(i, 0, line, StyleTitle)
+	}
+	menu.Tty.DrawHeader()
+	menu.Tty.PrintStyle(MenuFirstLine-2, 0, "         ------------------ Menu ------------------", StyleLabel)
+	menu.List.Draw(menu.Tty, MenuFirstLine, 18)
+	menu.Status.Draw(menu.Tty)
 }
 
 func (menu *MenuScreen) Init(tty *Terminal, ctx *ScreenContext) {
 	menu.Tty = tty
+	menu.Items = []MenuItem{
+		{'a', "Display subjects"},
+		{'b', "Search books by subjects"},
+		{'c', "Get book info"},
+		{'d', "Download book"},
+		{'e', "New book"},
+		{'q', "Quit"},
+	}
+	menu.List = NewListView(len(menu.Items), len(menu.Items), 40, func(index int) string {
+		return string(rune(menu.Items[index].Key)) + " - " + menu.Items[index].Label
+	})
+	menu.Status = &StatusBar{Help: "item key: select   " + Keys.Hints(ActionHelp, ActionOpen, ActionQuit)}
+}
+
+func (menu *MenuScreen) HelpActions() []Action {
+	return append([]Action{ActionOpen, ActionQuit}, ListActions...)
 }
 
 func (menu *MenuScreen) Run() {
 	menu.Tty.ClearScreen()
 	menu.PrintMenu()
-	menu.CursorY, menu.CursorX = menu.Tty.SaveCursorPos()
 	menu.Tty.BeginRead()
 }
 
@@ -66,245 +85,201 @@ func (menu *MenuScreen) OnScroll(y int) {
 }
 
 func (menu *MenuScreen) OnKey(key goncurses.Key) {
+	menu.Status.Clear()
+	if Keys.Is(key, ActionOpen) {
+		key = menu.Items[menu.List.Selected].Key
+	} else if Keys.Is(key, ActionQuit) {
+		key = 'q'
+	} else if menu.List.OnKey(key) {
+		menu.PrintMenu()
+		return
+	}
+
 	if key == 'a' {
 		menu.Tty.NewScreen(&SubjectsScreen{})
 	} else if key == 'q' {
-		menu.Tty.PrintMessage("Quit application...")
+		menu.Status.Message = "Quit application..."
 		menu.Tty.EndRead()
 	} else if key == 'b' {
-		menu.Tty.PrintMessage("Search books by subjects")
+		menu.Status.Message = "Search books by subjects"
 	} else if key == 'c' {
-		menu.Tty.PrintMessage("Get book info")
+		menu.Status.Message = "Get book info"
 	} else if key == 'd' {
-		menu.Tty.PrintMessage("Download book")
-
+		menu.Status.Message = "Download book"
+	} else if key == 'e' {
+		edit := &BookEditScreen{}
+		menu.Tty.NewScreen(edit)
+		if edit.Saved {
+			menu.Tty.NewScreen(&BookScreen{Tty: menu.Tty, BookId: edit.Book.Id})
+		}
 	} else {
-		menu.Tty.PrintMessage("Unrecognized command %d\n", key)
+		menu.Status.Err = fmt.Errorf("Unrecognized command %d", key)
 	}
-	menu.Tty.CursorAddress(menu.CursorY, menu.CursorX)
+	menu.PrintMenu()
 }
 
-func (menu *MenuScreen) OnRefresh(Lines, Cols int) {
-	menu.Tty.ClearScreen()
+// double-click runs command of item
+func (menu *MenuScreen) OnMouse(ev MouseEvent) {
+	if menu.List.OnMouse(ev, MenuFirstLine, 18) {
+		menu.OnKey(menu.Items[menu.List.Selected].Key)
+		return
+	}
 	menu.PrintMenu()
 }
 
-type SubjectsScreen struct {
-	Subjects []utils.Subject
-	Tty      *Terminal
+func (menu *MenuScreen) Crumb() string {
+	return "Menu"
 }
 
-func (subscr *SubjectsScreen) PrintSubjects() {
-	ctx := subscr.Tty.CurrentContext()
-	for i := 0; i < ctx.LogicCols; i += 1 {
-
-		for j := 0; j < subscr.Tty.Lines; j += 1 {
-
-			subscr.Tty.CursorAddress(j, i)
-
-			index := i*ctx.LogicLines + j + ctx.Scroll
-			subscr.PrintItem(index)
-		}
-
-		//tty.Printf("%s (%d)", subscr.Subjects[i].Name, subscr.Subjects[i].Id)
-		//tty.Printf("(%d[%d,%d])", i, line, col)
-	}
+func (menu *MenuScreen) OnRefresh(Lines, Cols int) {
+	menu.Tty.ClearScreen()
+	menu.PrintMenu()
 }
 
-func (subscr *SubjectsScreen) PrintItem(index int) {
-	if index < len(subscr.Subjects) {
-		subscr.Tty.Printf("%s (%d)", subscr.Subjects[index].Name, subscr.Subjects[index].Id)
-	}
+// books of subject Sub, of subjects SubjectIds if set, or of Tag.
+// '/' filters books: Visible holds indexes in BookLines of listed books
+type SubjectBooks struct {
+	Tty        *Terminal
+	BookLines  []utils.BookLine
+	Sub        *utils.Subject
+	SubjectIds []uint
+	Tag        string
+	Visible    []int
+	Filter     *Filter
+	List       *ListView
+	Status     *StatusBar
 }
 
-func (subscr *SubjectsScreen) Init(tty *Terminal, ctx *ScreenContext) {
-
-	subjects, err := utils.GetSubjects()
-	if err != nil {
-		tty.Printf("%v", err)
-	}
-	ctx.ColSize = 35
+func (subb *SubjectBooks) Init(t *Terminal, ctx *ScreenContext) {
 
-	if tty.Cols < ctx.ColSize {
-		ctx.ColSize = tty.Cols
+	var err error
+	if len(subb.Tag) > 0 {
+		subb.BookLines, err = utils.GetTagBooks(subb.Tag)
+	} else if len(subb.SubjectIds) > 0 {
+		subb.BookLines, err = utils.GetSubjectsBooks(subb.SubjectIds)
+	} else {
+		subb.BookLines, err = utils.GetSubjectBooks(int(subb.Sub.Id))
 	}
-	ctx.LogicCols = tty.Cols / ctx.ColSize
-	ctx.LogicLines = len(subjects) / ctx.LogicCols
-
-	if (ctx.LogicCols * ctx.LogicLines) < len(subjects) {
-		ctx.LogicLines += 1
+	subb.Tty = t
+	subb.Filter = NewFilter()
+	subb.List = NewListView(0, t.Lines-2, t.Cols, func(index int) string {
+		return subb.BookLines[subb.Visible[index]].Title
+	})
+	subb.ApplyFilter()
+	subb.Status = &StatusBar{Help: Keys.Hints(ActionHelp, ActionOpen, ActionFilter, ActionQuit)}
+	if err != nil {
+		subb.Status.Err = err
 	}
 
-	tty.ClearScreen()
-
-	subscr.Tty = tty
-	subscr.Subjects = subjects
+	subb.Tty.ClearScreen()
 }
 
-func (subscr *SubjectsScreen) Run() {
-
-	subscr.OnRefresh(0, 0)
-	if len(subscr.Subjects) > 0 {
-		subscr.Tty.Highlight(0, 0, subscr.Subjects[0].Name, true)
-	}
-	subscr.Tty.BeginRead()
+func (subb *SubjectBooks) Run() {
+	subb.OnRefresh(0, 0)
+	subb.Tty.BeginRead()
 }
 
-func (subscr *SubjectsScreen) OnScroll(y int) {
-
-	ctx := subscr.Tty.CurrentContext()
-	var offset int
-	if y < 0 {
-		y = -y
-		offset = 0
-	} else {
-		offset = subscr.Tty.Lines - y
-		y = subscr.Tty.Lines
+// lists books matching filter; selected book stays selected if it matches
+func (subb *SubjectBooks) ApplyFilter() {
+	selected := -1
+	if subb.List.Selected < len(subb.Visible) {
+		selected = subb.Visible[subb.List.Selected]
 	}
 
-	line := subscr.Tty.CurrentContext().CursorLine
-	col := subscr.Tty.CurrentContext().CursorCol
-
-	for i := offset; i < y; i += 1 {
-		for j := 0; j < ctx.LogicCols; j += 1 {
-			subscr.Tty.CursorAddress(i, j)
-			index := (ctx.LogicLines * j) + ctx.Scroll + i
-			subscr.PrintItem(index)
+	subb.Visible = nil
+	index := 0
+	for i, book := range subb.BookLines {
+		if subb.Filter.Match(book.Title) {
+			if i == selected {
+				index = len(subb.Visible)
+			}
+			subb.Visible = append(subb.Visible, i)
 		}
 	}
-	subscr.Tty.CursorAddress(line, col)
-
+	subb.List.SetCount(len(subb.Visible))
+	subb.List.Select(index)
 }
 
-func (subscr *SubjectsScreen) OnKey(key goncurses.Key) {
-	ctx := subscr.Tty.CurrentContext()
-	index := (ctx.CursorCol * ctx.LogicLines) + ctx.CursorLine + ctx.Scroll
-	if index < len(subscr.Subjects) {
-		subscr.Tty.Highlight(ctx.CursorLine, ctx.CursorCol, subscr.Subjects[index].Name, false)
-	}
-
-	switch key {
-	case 'q':
-		subscr.Tty.EndRead()
-	case goncurses.KEY_ESC:
-		subscr.Tty.EndRead()
-	case goncurses.KEY_UP:
-		subscr.Tty.MovePrevLine()
-	case goncurses.KEY_DOWN:
-		subscr.Tty.MoveNextLine()
-	case goncurses.KEY_RIGHT:
-		subscr.Tty.MoveNextCol()
-	case goncurses.KEY_LEFT:
-		subscr.Tty.MovePrevCol()
-	case goncurses.KEY_RETURN:
-		ctx := subscr.Tty.CurrentContext()
-		index := (ctx.CursorCol * ctx.LogicLines) + ctx.CursorLine + ctx.Scroll
-		if index < len(subscr.Subjects) {
-			sb := &SubjectBooks{}
-			sb.Sub = &subscr.Subjects[index]
-			subscr.Tty.NewScreen(sb)
-		}
-	}
-	index = (ctx.CursorCol * ctx.LogicLines) + ctx.CursorLine + ctx.Scroll
-	if index < len(subscr.Subjects) {
-		subscr.Tty.Highlight(ctx.CursorLine, ctx.CursorCol, subscr.Subjects[index].Name, true)
+func (subb *SubjectBooks) Title() string {
+	if len(subb.Tag) > 0 {
+		return "Tag: " + subb.Tag
 	}
+	return subb.Sub.Name
 }
 
-func (subscr *SubjectsScreen) OnRefresh(Lines, Cols int) {
-	subscr.Tty.ClearScreen()
-	subscr.PrintSubjects()
-	subscr.Tty.CursorAddress(0, 0)
+func (subb *SubjectBooks) Crumb() string {
+	return fmt.Sprintf("%s (%d books)", subb.Title(), len(subb.BookLines))
 }
 
-type SubjectBooks struct {
-	Tty       *Terminal
-	BookLines []utils.BookLine
-	Sub       *utils.Subject
-}
-
-func (subb *SubjectBooks) Init(t *Terminal, ctx *ScreenContext) {
-
-	subb.BookLines, _ = utils.GetSubjectBooks(int(subb.Sub.Id))
-	subb.Tty = t
-
-	ctx.LogicLines = len(subb.BookLines)
-
+func (subb *SubjectBooks) OnRefresh(lines, cols int) {
+	if lines > 0 {
+		subb.List.Resize(lines-2, cols)
+	}
 	subb.Tty.ClearScreen()
+	subb.Tty.DrawHeader()
+	subb.List.Draw(subb.Tty, 1, 0)
+	subb.Status.Draw(subb.Tty)
 }
 
-func (subb *SubjectBooks) Run() {
-	subb.Tty.CursorAddress(0, 0)
-	subb.OnRefresh(0, 0)
-	if len(subb.BookLines) > 0 {
-		subb.Tty.Highlight(0, 0, subb.BookLines[0].Title, true)
-	}
-	subb.Tty.BeginRead()
-}
+// actions of books list
+var SubjectBooksActions = append([]Action{ActionOpen, ActionFilter, ActionNextMatch, ActionPrevMatch, ActionQuit}, ListActions...)
 
-func (subb *SubjectBooks) OnRefresh(lines, cols int) {
+func (subb *SubjectBooks) HelpActions() []Action {
+	return SubjectBooksActions
+}
 
-	scroll := subb.Tty.CurrentContext().Scroll
-	lines = subb.Tty.Lines / subb.Tty.CurrentContext().LineSize
-	if (scroll + lines) > subb.Tty.CurrentContext().LogicLines {
-		lines = subb.Tty.CurrentContext().LogicLines - scroll
-	}
-	for line := 0; line < lines; line += 1 {
-		subb.Tty.CursorAddress(line, 0)
-		subb.Tty.Printf("%s", subb.BookLines[scroll+line].Title)
-	}
+func (subb *SubjectBooks) Typing() bool {
+	return subb.Filter.Editing
 }
 
 func (subb *SubjectBooks) OnScroll(y int) {
+	// list is scrolled by list view
+}
 
-	var line int
-	if y < 0 {
-		y = -y
-		line = 0
-	} else {
-		line = subb.Tty.Lines - y
-		y = subb.Tty.Lines
+func (subb *SubjectBooks) OpenSelected() {
+	if subb.List.Selected < len(subb.Visible) {
+		bs := &BookScreen{Tty: subb.Tty, BookId: int(subb.BookLines[subb.Visible[subb.List.Selected]].Id)}
+		subb.Tty.NewScreen(bs)
 	}
+}
 
-	for ; line < y; line += 1 {
-		subb.Tty.CursorAddress(line, 0)
-		index := line + subb.Tty.CurrentContext().Scroll
-		subb.Tty.Printf("%s", subb.BookLines[index].Title)
+// books are listed from line 1, double-click opens book
+func (subb *SubjectBooks) OnMouse(ev MouseEvent) {
+	if subb.List.OnMouse(ev, 1, 0) {
+		subb.OpenSelected()
 	}
+	subb.OnRefresh(0, 0)
 }
 
 func (subb *SubjectBooks) OnKey(k goncurses.Key) {
-	ctx := subb.Tty.CurrentContext()
-	index := ctx.CursorLine + ctx.Scroll
-	subb.Tty.Highlight(ctx.CursorLine, 0, subb.BookLines[index].Title, false)
+	if subb.Filter.OnKey(k) {
+		subb.ApplyFilter()
+		subb.Status.Message = subb.Filter.Text(len(subb.Visible))
+		subb.OnRefresh(0, 0)
+		return
+	}
 
-	switch k {
-	case goncurses.KEY_ESC:
-	case 'q':
+	count := len(subb.Visible)
+	action := Keys.Action(k, SubjectBooksActions...)
+	switch action {
+	case ActionQuit:
 		subb.Tty.EndRead()
-	case goncurses.KEY_DOWN:
-		subb.Tty.MoveNextLine()
-	case goncurses.KEY_UP:
-		subb.Tty.MovePrevLine()
-	case goncurses.KEY_PAGEDOWN:
-		subb.Tty.ScrollScr(subb.Tty.Lines)
-		if ctx.Scroll+subb.Tty.Lines >= len(subb.BookLines) {
-			ctx.CursorLine = subb.Tty.Lines - 1
-		}
-	case goncurses.KEY_PAGEUP:
-		subb.Tty.ScrollScr(-subb.Tty.Lines)
-		if ctx.Scroll == 0 {
-			ctx.CursorLine = 0
-		}
-	case goncurses.KEY_RETURN:
-		line := subb.Tty.CurrentContext().CursorLine + subb.Tty.CurrentContext().Scroll
-		if line >= 0 && line < len(subb.BookLines) {
-			bs := &BookScreen{subb.Tty, int(subb.BookLines[line].Id), nil, nil, ""}
-			subb.Tty.NewScreen(bs)
+		return
+	case ActionOpen:
+		subb.OpenSelected()
+	case ActionNextMatch, ActionPrevMatch:
+		// every listed book matches filter
+		if subb.Filter.Active() && count > 0 {
+			step := 1
+			if action == ActionPrevMatch {
+				step = count - 1
+			}
+			subb.List.Select((subb.List.Selected + step) % count)
 		}
+	default:
+		subb.List.OnKey(k)
 	}
-	index = ctx.CursorLine + ctx.Scroll
-	subb.Tty.Highlight(ctx.CursorLine, 0, subb.BookLines[index].Title, true)
+	subb.OnRefresh(0, 0)
 }
 
 type BookScreen struct {
@@ -312,165 +287,185 @@ type BookScreen struct {
 	BookId  int
 	BookObj *utils.Book
 	Err     error
-	Text    string
+	Text    []RichLine
+	Cover   image.Image
+	Tags    []string
+	Viewer  *TextViewer
+	Status  *StatusBar
 }
 
+// covers narrower than this are not drawn
+const MinCoverCols = 8
+
 func (bookscr *BookScreen) Init(t *Terminal, ctx *ScreenContext) {
 	bookscr.Tty = t
+	bookscr.Status = &StatusBar{Help: Keys.Hints(ActionHelp, ActionDownload, ActionEdit, ActionSubjects, ActionLinks, ActionQuit)}
 	bookscr.BookObj, bookscr.Err = utils.GetBook(bookscr.BookId)
+	if bookscr.Err != nil {
+		bookscr.Status.Err = bookscr.Err
+		bookscr.BookObj = &utils.Book{Id: bookscr.BookId}
+	}
+
+	bookscr.Tags, _ = utils.GetBookTags(bookscr.BookId)
 
-	bookscr.Text = t.FormatText(bookscr.BookObj.Description)
+	// no cover in store is not an error
+	bookscr.Cover, _ = download.Covers().LoadImage(bookscr.BookId)
 
-	ctx.LogicLines = strings.Count(bookscr.Text, "\n") + 8
+	bookscr.Viewer = &TextViewer{}
+	bookscr.Layout()
 	t.ClearScreen()
 }
 
+// wraps description for terminal size, beside cover if there is room for it
+func (bookscr *BookScreen) Layout() {
+	t := bookscr.Tty
+	text_cols := t.Cols
+	if bookscr.ShowCover() {
+		text_cols -= CoverWidth(t.CurrentContext()) + 2
+	}
+	bookscr.Text = RenderHtml(bookscr.BookObj.Description, text_cols)
+	bookscr.Viewer.Resize(t.Lines-DescriptionLine-1, text_cols)
+	bookscr.Viewer.SetLines(bookscr.Text)
+}
+
+func (bookscr *BookScreen) ShowCover() bool {
+	return bookscr.Cover != nil && CoverWidth(bookscr.Tty.CurrentContext()) >= MinCoverCols
+}
+
 func (bookscr *BookScreen) Run() {
 	bookscr.OnRefresh(0, 0)
 	bookscr.Tty.BeginRead()
 }
 
+// description is scrolled by text viewer, title and details stay on top
 func (bookscr *BookScreen) OnScroll(y int) {
-
-	if y < 0 {
-		y = -y
-
-		line := bookscr.Tty.CurrentContext().CursorLine
-		scroll := bookscr.Tty.CurrentContext().Scroll
-		bookscr.Tty.CursorAddress(0, 0)
-
-		for line := 0; line < y; line += 1 {
-
-			if line+scroll > 7 {
-
-			} else {
-
-			}
-		}
-
-		bookscr.Tty.CursorAddress(line, 0)
-	}
-
 }
 
 func (tty *Terminal) PrintTitle(title string) {
-	tty.CursorAddress(1, 0)
-	tty.Printf("Title:")
-	tty.CursorAddress(1, 30)
-	tty.Printf("\"%s\"", title)
+	tty.PrintStyle(1, 0, "Title:", StyleLabel)
+	tty.PrintStyle(1, 30, fmt.Sprintf("\"%s\"", title), StyleTitle)
 }
 
 func (tty *Terminal) PrintAuthors(authors string) {
-	tty.CursorAddress(2, 0)
-	tty.Printf("Authors:")
+	tty.PrintStyle(2, 0, "Authors:", StyleLabel)
 	tty.CursorAddress(2, 30)
 	tty.Printf("\"%s\"", authors)
 }
 
 func (tty *Terminal) PrintYear(year int) {
-	tty.CursorAddress(3, 0)
-	tty.Printf("Publication year:")
+	tty.PrintStyle(3, 0, "Publication year:", StyleLabel)
 	tty.CursorAddress(3, 30)
 	tty.Printf("%d", year)
 }
 
-func (tty *Terminal) PrintDescription(text string) {
-	tty.CursorAddress(5, 0)
-	tty.Printf("Description: ")
-	tty.CursorAddress(7, 0)
-	tty.Printf("%s\n(%d lines)", text, tty.CurrentContext().LogicLines)
+// ISBN, publisher, language and pages set by metadata enrichment
+func (tty *Terminal) PrintDetails(book *utils.Book) {
+	pages := ""
+	if book.Pages > 0 {
+		pages = strconv.Itoa(book.Pages)
+	}
+	tty.PrintField(4, "ISBN:", book.Isbn)
+	tty.PrintField(5, "Publisher:", book.Publisher)
+	tty.PrintField(6, "Language:", book.Language)
+	tty.PrintField(7, "Pages:", pages)
 }
 
-func (tty *Terminal) FormatText(text string) string {
-	re := strings.NewReplacer("<BR>", "\n", "<br>", "\n", "<p>", "\n", "</p>", "", "<ul>", "\n", "</ul>", "\n", "<li>", "\t+ ", "</li>", "\n")
-	formatted := re.Replace(text)
-	var curword, curline, all strings.Builder
-
-	if false {
-		return formatted
-	}
-	for _, r := range formatted {
-
-		switch r {
-		case ' ':
-			if curline.Len()+curword.Len() >= tty.Cols-1 {
-				curline.WriteString("\n")
-				all.WriteString(curline.String())
-				curline.Reset()
-			}
-			curline.WriteString(curword.String())
-			curword.Reset()
-			if curline.Len()+1 < tty.Cols {
-				curline.WriteRune(r)
-			}
-		case '\n':
-			if curline.Len()+1 < tty.Cols {
-				curline.WriteString(" ")
-			} else {
-				curline.WriteString("\n")
-				all.WriteString(curline.String())
-				curline.Reset()
-			}
+func (tty *Terminal) PrintField(line int, label, value string) {
+	tty.PrintStyle(line, 0, label, StyleLabel)
+	tty.CursorAddress(line, 30)
+	tty.Printf("%s", value)
+}
 
-		case '\t':
-			// handle word
-			if curline.Len()+curword.Len() >= tty.Cols-1 {
-				all.WriteString("\n")
-				all.WriteString(curline.String())
-				curline.Reset()
-			}
-			curline.WriteString(curword.String())
-			curword.Reset()
-
-			tabstr := "    "
-			if curline.Len()+len(tabstr) < tty.Cols-1 {
-				curline.WriteString(tabstr)
-			} else {
-				all.WriteString("\n")
-				all.WriteString(curline.String())
-				curline.Reset()
-			}
+// first line of book description text
+const DescriptionLine = 11
 
-		default:
-			curword.WriteRune(r)
-		}
+func (tty *Terminal) PrintDescription(viewer *TextViewer) {
+	tty.PrintStyle(DescriptionLine-2, 0, "Description: ", StyleLabel)
+	if position := viewer.Position(); len(position) > 0 {
+		tty.PrintStyle(DescriptionLine-2, 13, position, StyleDim)
 	}
+	viewer.Draw(tty, DescriptionLine, 0)
+}
 
-	return all.String()
+func (bookscr *BookScreen) Crumb() string {
+	if bookscr.BookObj == nil || len(bookscr.BookObj.Title) == 0 {
+		return fmt.Sprintf("Book %d", bookscr.BookId)
+	}
+	return bookscr.BookObj.Title
 }
 
 func (bookscr *BookScreen) OnRefresh(lins, cols int) {
 	tty := bookscr.Tty
+	if lins > 0 {
+		bookscr.Layout()
+	}
 
 	tty.ClearScreen()
+	tty.DrawHeader()
 
 	book := bookscr.BookObj
 
-	if book != nil {
-		tty.PrintTitle(book.Title)
-		tty.PrintAuthors(book.Authors)
-		tty.PrintYear(book.Year)
-		tty.PrintDescription(bookscr.Text)
+	tty.PrintTitle(book.Title)
+	tty.PrintAuthors(book.Authors)
+	tty.PrintYear(book.Year)
+	tty.PrintDetails(book)
+	tty.PrintField(8, "Tags:", strings.Join(bookscr.Tags, ", "))
+	tty.PrintDescription(bookscr.Viewer)
+
+	if bookscr.ShowCover() {
+		cover_cols := CoverWidth(tty.CurrentContext())
+		tty.DrawCover(1, tty.Cols-cover_cols-1, bookscr.Cover, cover_cols)
 	}
-	if bookscr.Err != nil {
-		tty.Printf("%v", bookscr.Err)
+	bookscr.Status.Draw(tty)
+}
+
+// reloads book after editing
+func (bookscr *BookScreen) Reload() {
+	bookscr.Init(bookscr.Tty, bookscr.Tty.CurrentContext())
+	bookscr.OnRefresh(0, 0)
+}
+
+// actions of book screen, description is scrolled with list actions
+var BookActions = append([]Action{ActionDownload, ActionEdit, ActionSubjects, ActionLinks, ActionQuit}, ListActions...)
+
+func (bookscr *BookScreen) HelpActions() []Action {
+	return BookActions
+}
+
+// wheel scrolls description
+func (bookscr *BookScreen) OnMouse(ev MouseEvent) {
+	if bookscr.Viewer.OnMouse(ev) {
+		bookscr.OnRefresh(0, 0)
 	}
-	tty.CursorAddress(0, 0)
 }
 
 func (bookscr *BookScreen) OnKey(k goncurses.Key) {
-	switch k {
-	case 'q':
+	switch Keys.Action(k, BookActions...) {
+	case ActionQuit:
 		bookscr.Tty.EndRead()
-	case 'd':
+		return
+	case ActionDownload:
 		dl := &DownloadScreen{}
 		dl.BookId = bookscr.BookId
 		bookscr.Tty.NewScreen(dl)
-	case goncurses.KEY_PAGEDOWN:
-		bookscr.Tty.ScrollScr(bookscr.Tty.Lines)
-	case goncurses.KEY_PAGEUP:
-		bookscr.Tty.ScrollScr(-bookscr.Tty.Lines)
+	case ActionEdit:
+		if bookscr.Err == nil {
+			edit := &BookEditScreen{Book: bookscr.BookObj}
+			bookscr.Tty.NewScreen(edit)
+			if edit.Saved {
+				bookscr.Reload()
+			}
+		}
+	case ActionSubjects:
+		bookscr.Tty.NewScreen(&BookSubjectsScreen{BookId: bookscr.BookId})
+	case ActionLinks:
+		bookscr.Tty.NewScreen(&BookLinksScreen{BookId: bookscr.BookId})
+	default:
+		if !bookscr.Viewer.OnKey(k) {
+			return
+		}
 	}
+	bookscr.OnRefresh(0, 0)
 }
 
 type DownloadScreen struct {
@@ -478,13 +473,25 @@ type DownloadScreen struct {
 	BookId int
 	BookDl *utils.BookDownload
 	Done   bool
+	Dialog *ConfirmDialog
+	Status *StatusBar
 }
 
 func (ds *DownloadScreen) Init(tty *Terminal, ctx *ScreenContext) {
 	ds.Tty = tty
-	ds.BookDl, _ = utils.GetDownloadInfo(ds.BookId)
+	ds.Status = &StatusBar{}
+	ds.Dialog = &ConfirmDialog{Message: "Download book?", Default: true}
+
+	var err error
+	ds.BookDl, err = utils.GetDownloadInfo(ds.BookId)
+	if err != nil {
+		ds.Status.Err = fmt.Errorf("%v. Press any key to return to book page", err)
+		ds.Done = true
+	} else {
+		ds.Status.Message = ds.Dialog.Text()
+		ds.Done = false
+	}
 	tty.ClearScreen()
-	ds.Done = false
 }
 
 func (ds *DownloadScreen) Run() {
@@ -492,46 +499,50 @@ func (ds *DownloadScreen) Run() {
 	ds.Tty.BeginRead()
 }
 
+func (ds *DownloadScreen) HelpActions() []Action {
+	return []Action{ActionYes, ActionNo}
+}
+
 func (ds *DownloadScreen) OnKey(key goncurses.Key) {
-	switch key {
-	case 'n':
+	if ds.Done {
 		ds.Tty.EndRead()
-	case 'y':
-		err := download.DownloadFile(ds.BookDl)
-		if err != nil {
-			ds.Tty.CursorAddress(7, 0)
-			ds.Tty.Printf("%v\n", err)
-		}
-		ds.Done = true
-		ds.Tty.MoveNextLine()
-		ds.Tty.Println("Press any key to return to book page")
-	default:
-		if ds.Done {
-			ds.Tty.EndRead()
-		}
+		return
+	}
+	if !ds.Dialog.OnKey(key) {
+		return
+	}
+	if !ds.Dialog.Yes {
+		ds.Tty.EndRead()
+		return
 	}
 
+	ds.Status.Message = "Downloading..."
+	ds.Status.Draw(ds.Tty)
+	err := download.DownloadFile(ds.BookDl)
+	if err != nil {
+		ds.Status.Err = fmt.Errorf("%v. Press any key to return to book page", err)
+	} else {
+		ds.Status.Message = "Book downloaded. Press any key to return to book page"
+	}
+	ds.Done = true
+	ds.OnRefresh(0, 0)
 }
 
 func (ds *DownloadScreen) OnScroll(y int) {
 	// nop
 }
 
+func (ds *DownloadScreen) Crumb() string {
+	return "Download"
+}
+
 func (ds *DownloadScreen) OnRefresh(lines, cols int) {
-	ds.Tty.CursorAddress(0, 0)
-	ds.Tty.Printf("File:")
-	ds.Tty.CursorAddress(0, 30)
-	ds.Tty.Printf("%s", ds.BookDl.FileName)
-	ds.Tty.CursorAddress(1, 0)
-	ds.Tty.Printf("Size:")
-	ds.Tty.CursorAddress(1, 30)
-	ds.Tty.Printf("%d", ds.BookDl.FileSize)
-	ds.Tty.CursorAddress(2, 0)
-	ds.Tty.Printf("Storage vendor:")
-	ds.Tty.CursorAddress(2, 30)
-	ds.Tty.Printf("%s (%s)", ds.BookDl.Vendor, ds.BookDl.VendorCode)
-
-	ds.Tty.CursorAddress(4, 0)
-	ds.Tty.Println("Download book? Y/n")
-	ds.Tty.MoveNextLine()
+	ds.Tty.ClearScreen()
+	ds.Tty.DrawHeader()
+	if ds.BookDl != nil {
+		ds.Tty.PrintField(1, "File:", ds.BookDl.FileName)
+		ds.Tty.PrintField(2, "Size:", strconv.Itoa(ds.BookDl.FileSize))
+		ds.Tty.PrintField(3, "Storage vendor:", fmt.Sprintf("%s (%s)", ds.BookDl.Vendor, ds.BookDl.VendorCode))
+	}
+	ds.Status.Draw(ds.Tty)
 }
@@ -0,0 +1,498 @@
+package console_test
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/config"
+	"github.com/bookstore-go/console"
+	"github.com/bookstore-go/utils"
+	"github.com/rthornton128/goncurses"
+)
+
+// line of screen text saved by virtual screen
+func shotLine(shot string, line int) string {
+	lines := strings.Split(shot, "\n")
+	if line >= len(lines) {
+		return ""
+	}
+	return lines[line]
+}
+
+func subjectsQueries() []fakeQuery {
+	return []fakeQuery{
+		{"FROM IT_SUBJECT", [][]driver.Value{
+			{int64(1), "Programming", int64(0)},
+			{int64(2), "Go", int64(1)},
+			{int64(3), "Databases", int64(0)},
+		}},
+		{"SELECT SUBJECT_ID,BOOK_ID", [][]driver.Value{
+			{int64(1), int64(10)},
+			{int64(2), int64(11)},
+		}},
+		{"GROUP BY TAG", [][]driver.Value{
+			{"reference", int64(1)},
+		}},
+		{"SELECT DISTINCT B.ID", [][]driver.Value{
+			{int64(11), "Learning Go"},
+			{int64(12), "Go in Action"},
+			{int64(13), "Database Internals"},
+		}},
+	}
+}
+
+func TestMenuScreen(t *testing.T) {
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	vs.SendKeys(goncurses.KEY_DOWN, 'b', 'x')
+	tty.NewScreen(&console.MenuScreen{})
+
+	if len(vs.Shots) != 4 {
+		t.Fatalf("4 keys should be read (got %d)\n", len(vs.Shots))
+	}
+	if line := shotLine(vs.Shots[0], console.MenuFirstLine); line != "                  a - Display subjects" {
+		t.Fatalf("First menu item expected (got '%s')\n", line)
+	}
+	if line := shotLine(vs.Shots[0], 0); line != "Menu" {
+		t.Fatalf("Menu header expected (got '%s')\n", line)
+	}
+	if line := shotLine(vs.Shots[0], 23); !strings.HasPrefix(line, "item key: select   ?: help   RETURN: open   q: quit ") {
+		t.Fatalf("Help expected in status bar (got '%s')\n", line)
+	}
+	if line := shotLine(vs.Shots[2], 23); !strings.HasPrefix(line, "Search books by subjects ") {
+		t.Fatalf("Message expected in status bar (got '%s')\n", line)
+	}
+	if line := shotLine(vs.Shots[3], 23); !strings.HasPrefix(line, "Unrecognized command 120 ") {
+		t.Fatalf("Unknown key should be reported (got '%s')\n", line)
+	}
+}
+
+func TestSubjectsScreen(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	vs.SendKeys(goncurses.KEY_DOWN, goncurses.KEY_RIGHT, goncurses.KEY_DOWN, goncurses.KEY_RETURN)
+	tty.NewScreen(&console.SubjectsScreen{})
+
+	shot := vs.Shots[0]
+	if shotLine(shot, 0) != "Subjects" || shotLine(shot, 1) != "  Databases (0)" || shotLine(shot, 2) != "+ Programming (2)" || shotLine(shot, 3) != "+ Tags (1)" {
+		t.Fatalf("Collapsed tree expected (got\n%s)\n", shot)
+	}
+
+	shot = vs.Shots[2]
+	if shotLine(shot, 2) != "- Programming (2)" || shotLine(shot, 3) != "    Go (1)" || shotLine(shot, 4) != "+ Tags (1)" {
+		t.Fatalf("Programming should be expanded (got\n%s)\n", shot)
+	}
+
+	// books of Go
+	shot = vs.LastShot()
+	if shotLine(shot, 0) != "Subjects > Go (3 books)" || shotLine(shot, 1) != "Learning Go" {
+		t.Fatalf("Books of Go expected (got\n%s)\n", shot)
+	}
+}
+
+func TestSubjectsScreenFilter(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	vs.SendKeys('/', 'g', 'o', goncurses.KEY_RETURN, goncurses.KEY_ESC)
+	tty.NewScreen(&console.SubjectsScreen{})
+
+	// after RETURN
+	shot := vs.Shots[4]
+	if shotLine(shot, 1) != "- Programming (2)" || shotLine(shot, 2) != "    Go (1)" || shotLine(shot, 3) != "" {
+		t.Fatalf("Go and its parent expected (got\n%s)\n", shot)
+	}
+	if line := shotLine(shot, 23); !strings.HasPrefix(line, "/go: 1 matches") {
+		t.Fatalf("Number of matches expected (got '%s')\n", line)
+	}
+
+	// ESC clears filter, Programming stays expanded
+	shot = vs.LastShot()
+	if shotLine(shot, 1) != "  Databases (0)" || shotLine(shot, 3) != "    Go (1)" || shotLine(shot, 4) != "+ Tags (1)" {
+		t.Fatalf("Whole tree expected (got\n%s)\n", shot)
+	}
+}
+
+func TestSubjectBooksFilter(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	books := &console.SubjectBooks{Sub: &utils.Subject{Id: 2, Name: "Go"}, SubjectIds: []uint{2}}
+	vs.SendKeys('/', 'g', 'o', goncurses.KEY_RETURN, 'n')
+	tty.NewScreen(books)
+
+	shot := vs.LastShot()
+	if shotLine(shot, 1) != "Learning Go" || shotLine(shot, 2) != "Go in Action" || shotLine(shot, 3) != "" {
+		t.Fatalf("Books matching 'go' expected (got\n%s)\n", shot)
+	}
+	if len(books.Visible) != 2 || books.Visible[1] != 1 || books.List.Selected != 1 {
+		t.Fatalf("Second book should be selected (got %v, %d)\n", books.Visible, books.List.Selected)
+	}
+}
+
+func TestBookScreen(t *testing.T) {
+	workdir := t.TempDir()
+	get_config := config.GetConfig
+	t.Cleanup(func() { config.GetConfig = get_config })
+	config.GetConfig = func() config.Config {
+		var conf config.Config
+		conf.Dirs.Workdir = workdir
+		return conf
+	}
+
+	var words []string
+	for i := 1; i <= 600; i += 1 {
+		words = append(words, fmt.Sprintf("w%d", i))
+	}
+	description := strings.Join(words, " ")
+	useFakeDb(t,
+		fakeQuery{"FROM BOOKS WHERE ID=3", [][]driver.Value{
+			{int64(3), "The Go Programming Language", "Alan Donovan, Brian Kernighan", int64(2015), description, "9780134190440", "Addison-Wesley", "en", int64(380)},
+		}},
+		fakeQuery{"FROM BOOKS_TAGS WHERE BOOK_ID", [][]driver.Value{
+			{"golang"},
+			{"reference"},
+		}},
+	)
+
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+	vs.SendKeys(goncurses.KEY_PAGEDOWN)
+	tty.NewScreen(&console.BookScreen{BookId: 3})
+
+	shot := vs.Shots[0]
+	if !strings.Contains(shotLine(shot, 1), "The Go Programming Language") {
+		t.Fatalf("Title expected on line 1 (got '%s')\n", shotLine(shot, 1))
+	}
+	if !strings.HasSuffix(shotLine(shot, 5), "Addison-Wesley") || !strings.HasSuffix(shotLine(shot, 8), "golang, reference") {
+		t.Fatalf("Details and tags expected (got\n%s)\n", shot)
+	}
+
+	text := console.RenderHtml(description, 80)
+	first := text[0].Text()
+	if line := shotLine(shot, console.DescriptionLine); line != first {
+		t.Fatalf("Description should start on line %d (got '%s', want '%s')\n", console.DescriptionLine, line, first)
+	}
+
+	// description lines are 11 to 22: page down shows line 12 of text first
+	page := text[12].Text()
+	if line := shotLine(vs.LastShot(), console.DescriptionLine); line != page {
+		t.Fatalf("Description should be scrolled (got '%s', want '%s')\n", line, page)
+	}
+	if line := shotLine(vs.LastShot(), console.DescriptionLine-2); !strings.HasPrefix(line, "Description: lines 13-24 of ") {
+		t.Fatalf("Scroll position expected (got '%s')\n", line)
+	}
+	if line := shotLine(vs.LastShot(), 1); !strings.Contains(line, "The Go Programming Language") {
+		t.Fatalf("Title should not scroll (got '%s')\n", line)
+	}
+}
+
+func TestTerminalResize(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	vs.SetSize(12, 40)
+	tty.NewScreen(&console.SubjectsScreen{})
+
+	if tty.Lines != 12 || tty.Cols != 40 {
+		t.Fatalf("Terminal size should be 12x40 (got %dx%d)\n", tty.Lines, tty.Cols)
+	}
+	shot := vs.LastShot()
+	if lines := strings.Split(shot, "\n"); len(lines) != 12 {
+		t.Fatalf("12 lines expected (got %d)\n", len(lines))
+	}
+	if line := shotLine(shot, 11); !strings.HasPrefix(line, "?: help   RIGHT: expand") || len(line) > 39 {
+		t.Fatalf("Status bar should be on last line (got '%s')\n", line)
+	}
+}
+
+func TestHelpOverlay(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	// '?' typed in filter is part of pattern
+	vs.SendKeys('?', 'x', '/', '?', goncurses.KEY_ESC)
+	tty.NewScreen(&console.SubjectsScreen{})
+
+	shot := vs.Shots[1]
+	if !strings.Contains(shot, "Keys (any key to close)") || !strings.Contains(shot, "RIGHT/+       expand") || !strings.Contains(shot, "q/ESC         quit") {
+		t.Fatalf("Help overlay expected (got\n%s)\n", shot)
+	}
+	if strings.Contains(shot, "download") {
+		t.Fatalf("Only actions of subjects screen expected (got\n%s)\n", shot)
+	}
+
+	// key closing help is not read: tree is still displayed
+	shot = vs.Shots[2]
+	if strings.Contains(shot, "Keys (any key") || shotLine(shot, 1) != "  Databases (0)" {
+		t.Fatalf("Help should be closed (got\n%s)\n", shot)
+	}
+	if line := shotLine(vs.Shots[4], 23); !strings.HasPrefix(line, "/? ") {
+		t.Fatalf("'?' should be typed in filter (got '%s')\n", line)
+	}
+}
+
+func TestViKeys(t *testing.T) {
+	useFakeDb(t, subjectsQueries()...)
+	keys := console.Keys
+	t.Cleanup(func() { console.Keys = keys })
+	err := console.LoadKeys(map[string]interface{}{"preset": "vi"})
+	if err != nil {
+		t.Fatalf("vi preset should load (got %v)\n", err)
+	}
+
+	// rows: Databases, Programming, Tags
+	tests := []struct {
+		keys []goncurses.Key
+		want int
+	}{
+		{[]goncurses.Key{'G'}, 2},
+		{[]goncurses.Key{'G', 'k'}, 1},
+		{[]goncurses.Key{'G', 'g', 'j'}, 1},
+		{[]goncurses.Key{goncurses.KEY_DOWN, goncurses.KEY_END}, 2},
+	}
+	for _, test := range tests {
+		vs := console.NewVirtualScreen(24, 80)
+		tty := console.NewBackendTerminal(vs)
+		subscr := &console.SubjectsScreen{}
+		vs.SendKeys(test.keys...)
+		tty.NewScreen(subscr)
+		if subscr.List.Selected != test.want {
+			t.Fatalf("Keys %v should select row %d (got %d)\n", test.keys, test.want, subscr.List.Selected)
+		}
+	}
+}
+
+func TestDedupeExclude(t *testing.T) {
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	cluster := []*utils.Book{{Id: 7, Title: "Go in Action"}, {Id: 8, Title: "Go in action", Year: 2016}}
+	ds := &console.DedupeScreen{Clusters: [][]*utils.Book{cluster}}
+	vs.SendKeys(' ', 'm', goncurses.KEY_DOWN, 'm')
+	tty.NewScreen(ds)
+
+	if !ds.Excluded[7] || ds.Excluded[8] {
+		t.Fatalf("Book 7 should be excluded (got %v)\n", ds.Excluded)
+	}
+	shot := vs.Shots[2]
+	if line := shotLine(shot, console.DedupeFirstLine); !strings.HasPrefix(line, "x      7  Go in Action") {
+		t.Fatalf("Excluded book should be marked (got '%s')\n", line)
+	}
+	if line := shotLine(shot, 23); !strings.HasPrefix(line, "Book 7 to keep is excluded") {
+		t.Fatalf("Excluded book cannot be kept (got '%s')\n", line)
+	}
+	if line := shotLine(vs.LastShot(), 23); !strings.HasPrefix(line, "No book to merge") {
+		t.Fatalf("Nothing to merge expected (got '%s')\n", line)
+	}
+}
+
+func TestBookEditDescription(t *testing.T) {
+	useFakeDb(t)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	book := &utils.Book{Id: 3, Title: "Go in Action", Description: "first\nsecond"}
+	be := &console.BookEditScreen{Book: book}
+	vs.SendKeys(goncurses.KEY_F2, 27)
+	tty.NewScreen(be)
+
+	shot := vs.Shots[0]
+	if line := shotLine(shot, console.EditFirstLine+3); !strings.Contains(line, "first"+console.LineBreakMark+"second") {
+		t.Fatalf("Line breaks of description should be marked (got '%s')\n", line)
+	}
+	if got := be.Form.Fields[3].Text(); got != "first"+console.LineBreakMark+"second" {
+		t.Fatalf("Description field mismatch (got '%s')\n", got)
+	}
+
+	// book and tags are saved in one transaction: nothing is saved when it fails
+	if line := shotLine(vs.LastShot(), 23); !strings.HasPrefix(line, "Fake database is read only") || be.Saved {
+		t.Fatalf("Save should fail on read only database (got '%s')\n", line)
+	}
+	if book.Description != "first\nsecond" {
+		t.Fatalf("Book should not change (got '%s')\n", book.Description)
+	}
+}
+
+// statements executed on fake database, in order
+func checkStatements(t *testing.T, db *fakeDriver, want []string) {
+	got := db.Statements()
+	if len(got) != len(want) {
+		t.Fatalf("%d statements expected (got\n%s)\n", len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Statement %d mismatch (want '%s', got '%s')\n", i, want[i], got[i])
+		}
+	}
+	for _, e := range db.Execs {
+		if !e.InTx {
+			t.Fatalf("Statement should run in transaction (got '%s')\n", e.Sql)
+		}
+	}
+}
+
+func TestDedupeMerge(t *testing.T) {
+	workdir := t.TempDir()
+	get_config := config.GetConfig
+	t.Cleanup(func() { config.GetConfig = get_config })
+	config.GetConfig = func() config.Config {
+		var conf config.Config
+		conf.Dirs.Workdir = workdir
+		return conf
+	}
+	db := useWritableFakeDb(t)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	cluster := []*utils.Book{{Id: 7, Title: "Go in Action"}, {Id: 8, Title: "Go in action", Year: 2016}}
+	vs.SendKeys('m', 'y')
+	tty.NewScreen(&console.DedupeScreen{Clusters: [][]*utils.Book{cluster}})
+
+	// rows of book 8 already set for book 7 are deleted before the others are moved
+	checkStatements(t, db, []string{
+		"DELETE D FROM BOOKS_SUBJECTS_ASSOC D JOIN BOOKS_SUBJECTS_ASSOC K ON K.SUBJECT_ID=D.SUBJECT_ID AND K.BOOK_ID=? WHERE D.BOOK_ID=? [7 8]",
+		"UPDATE BOOKS_SUBJECTS_ASSOC SET BOOK_ID=? WHERE BOOK_ID=? [7 8]",
+		"DELETE D FROM BOOKS_TAGS D JOIN BOOKS_TAGS K ON K.TAG=D.TAG AND K.BOOK_ID=? WHERE D.BOOK_ID=? [7 8]",
+		"UPDATE BOOKS_TAGS SET BOOK_ID=? WHERE BOOK_ID=? [7 8]",
+		"DELETE D FROM BOOKS_LINKS D JOIN BOOKS_LINKS K ON K.STORE_ID=D.STORE_ID AND K.BOOK_ID=? WHERE D.BOOK_ID=? [7 8]",
+		"UPDATE BOOKS_LINKS SET BOOK_ID=? WHERE BOOK_ID=? [7 8]",
+		"DELETE FROM BOOKS WHERE ID=? [8]",
+	})
+	if db.Commits != 1 || db.Rollbacks != 0 {
+		t.Fatalf("Merge should be committed (got %d commits, %d rollbacks)\n", db.Commits, db.Rollbacks)
+	}
+	if line := shotLine(vs.LastShot(), 23); !strings.HasPrefix(line, "Books [8] merged into book 7") {
+		t.Fatalf("Merge message expected (got '%s')\n", line)
+	}
+}
+
+func TestDedupeMergeFailure(t *testing.T) {
+	db := useWritableFakeDb(t)
+	db.Fail = "DELETE FROM BOOKS "
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	cluster := []*utils.Book{{Id: 7, Title: "Go in Action"}, {Id: 8, Title: "Go in action", Year: 2016}}
+	ds := &console.DedupeScreen{Clusters: [][]*utils.Book{cluster}}
+	vs.SendKeys('m', 'y')
+	tty.NewScreen(ds)
+
+	if db.Commits != 0 || db.Rollbacks != 1 {
+		t.Fatalf("Merge should be rolled back (got %d commits, %d rollbacks)\n", db.Commits, db.Rollbacks)
+	}
+	if line := shotLine(vs.LastShot(), 23); !strings.HasPrefix(line, "Merge failed: Fake statement failure") || len(ds.Clusters) != 1 {
+		t.Fatalf("Cluster should stay after failure (got '%s')\n", line)
+	}
+}
+
+func TestBookEditSave(t *testing.T) {
+	db := useWritableFakeDb(t)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	// new book: title, description with a line break mark, tags
+	keys := []goncurses.Key{'G', 'o', goncurses.KEY_DOWN, goncurses.KEY_DOWN, goncurses.KEY_DOWN, 'a'}
+	for _, b := range []byte(console.LineBreakMark) {
+		keys = append(keys, goncurses.Key(b))
+	}
+	keys = append(keys, 'b', goncurses.KEY_DOWN, 'G', 'o', ',', 'x', goncurses.KEY_F2)
+	vs.SendKeys(keys...)
+	be := &console.BookEditScreen{}
+	tty.NewScreen(be)
+
+	checkStatements(t, db, []string{
+		"INSERT INTO BOOKS (TITLE,AUTHORS,YEAR,DESCR) VALUES (?,?,?,?) [Go  0 a\nb]",
+		fmt.Sprintf("DELETE FROM BOOKS_TAGS WHERE BOOK_ID=? [%d]", fakeInsertId),
+		fmt.Sprintf("INSERT INTO BOOKS_TAGS (BOOK_ID,TAG) VALUES (?,?) [%d go]", fakeInsertId),
+		fmt.Sprintf("INSERT INTO BOOKS_TAGS (BOOK_ID,TAG) VALUES (?,?) [%d x]", fakeInsertId),
+	})
+	if db.Commits != 1 || !be.Saved || be.Book.Id != fakeInsertId || be.Book.Description != "a\nb" {
+		t.Fatalf("Book should be saved in one transaction (got %d commits, book %#v)\n", db.Commits, be.Book)
+	}
+}
+
+func TestBookEditSaveFailure(t *testing.T) {
+	db := useWritableFakeDb(t)
+	db.Fail = "INSERT INTO BOOKS_TAGS"
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	book := &utils.Book{Id: 3, Title: "Go"}
+	be := &console.BookEditScreen{Book: book}
+	vs.SendKeys('!', goncurses.KEY_UP, 'x', goncurses.KEY_F2, 27)
+	tty.NewScreen(be)
+
+	// book update is rolled back with failed tags
+	if db.Commits != 0 || db.Rollbacks != 1 || be.Saved || book.Title != "Go" {
+		t.Fatalf("Save should be rolled back (got %d commits, %d rollbacks, title '%s')\n", db.Commits, db.Rollbacks, book.Title)
+	}
+	if line := shotLine(vs.LastShot(), 23); !strings.HasPrefix(line, "Fake statement failure") {
+		t.Fatalf("Error expected in status bar (got '%s')\n", line)
+	}
+}
+
+func TestBookSubjectsSave(t *testing.T) {
+	db := useWritableFakeDb(t,
+		fakeQuery{"A.BOOK_ID=?", [][]driver.Value{{int64(3), "Databases"}}},
+		fakeQuery{"FROM IT_SUBJECT", [][]driver.Value{
+			{int64(1), "Programming", int64(0)},
+			{int64(2), "Go", int64(1)},
+			{int64(3), "Databases", int64(0)},
+		}},
+	)
+	vs := console.NewVirtualScreen(24, 80)
+	tty := console.NewBackendTerminal(vs)
+
+	vs.SendKeys(' ', goncurses.KEY_F2)
+	tty.NewScreen(&console.BookSubjectsScreen{BookId: 5})
+
+	checkStatements(t, db, []string{
+		"DELETE FROM BOOKS_SUBJECTS_ASSOC WHERE BOOK_ID=? [5]",
+		"INSERT INTO BOOKS_SUBJECTS_ASSOC (BOOK_ID,SUBJECT_ID) VALUES (?,?) [5 1]",
+		"INSERT INTO BOOKS_SUBJECTS_ASSOC (BOOK_ID,SUBJECT_ID) VALUES (?,?) [5 3]",
+	})
+	if db.Commits != 1 {
+		t.Fatalf("Subjects should be saved in one transaction (got %d commits)\n", db.Commits)
+	}
+}
+
+func TestLinkEditSave(t *testing.T) {
+	vendors := fakeQuery{"FROM FILE_STORE", [][]driver.Value{{int64(2), "Google", "GOOG", int64(0), "work"}}}
+
+	tests := []struct {
+		links   int64
+		want    []string
+		message string
+	}{
+		{0, []string{"INSERT INTO BOOKS_LINKS (BOOK_ID,STORE_ID,FILE_ID,FILE_SIZE,FILE_NAME) VALUES (?,?,?,?,?) [5 2 abc 0 a.pdf]"}, ""},
+		// one link by store and book
+		{1, nil, "Book 5 already has a link in store 2"},
+	}
+	for _, test := range tests {
+		db := useWritableFakeDb(t, vendors, fakeQuery{"SELECT COUNT(*) FROM BOOKS_LINKS", [][]driver.Value{{test.links}}})
+		vs := console.NewVirtualScreen(24, 80)
+		tty := console.NewBackendTerminal(vs)
+
+		link := &utils.BookDownload{BookId: 5}
+		vs.SendKeys('2', goncurses.KEY_DOWN, 'a', 'b', 'c', goncurses.KEY_DOWN, 'a', '.', 'p', 'd', 'f', goncurses.KEY_F2, 27)
+		tty.NewScreen(&console.LinkEditScreen{Link: link})
+
+		checkStatements(t, db, test.want)
+		if line := shotLine(vs.LastShot(), 23); !strings.HasPrefix(line, test.message) {
+			t.Fatalf("Status mismatch (want '%s', got '%s')\n", test.message, line)
+		}
+		if saved := link.StorageId == 2; saved != (len(test.want) > 0) {
+			t.Fatalf("Link should be updated only when saved (got %#v)\n", link)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package console
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/rthornton128/goncurses"
@@ -9,29 +10,28 @@ import (
 func init() {
 }
 
+// no more keys to read: screens end
+const KeyEOF goncurses.Key = -1
+
 func NewTerminal() *Terminal {
 	term_name, exist := os.LookupEnv("TERM")
 	if !exist || len(term_name) == 0 {
 		panic("TERM env not found")
 	}
 
-	goncurses.Init()
-
-	goncurses.CBreak(true)
-	goncurses.Cursor(0)
-
-	term := Terminal{0, 0, []*ScreenContext{}, nil}
-
-	term.Lines, term.Cols = GetScreenSize()
+	return NewBackendTerminal(NewCursesBackend())
+}
 
-	win, _ := goncurses.NewWindow(term.Lines, term.Cols, 0, 0)
+// terminal drawing on backend, VirtualScreen to run screens without curses
+func NewBackendTerminal(backend Backend) *Terminal {
+	term := Terminal{0, 0, []*ScreenContext{}, backend, 0, nil, 1, nil, 1}
 
-	win.Keypad(true)
-	win.ScrollOk(true)
-	term.Win = win
+	term.Lines, term.Cols = backend.Size()
+	term.Colors = backend.Colors()
+	backend.Resize(term.Lines, term.Cols)
+	term.SetTheme(CurrentTheme)
 
 	return &term
-
 }
 
 type ScreenContext struct {
@@ -44,62 +44,104 @@ type ScreenContext struct {
 	Reading       bool
 	CursorLine    int
 	CursorCol     int
+	// terminal was resized while screen was not on top
+	Resized bool
 }
 
 type Terminal struct {
 	Cols        int
 	Lines       int
 	ScreenStack []*ScreenContext
-	Win         *goncurses.Window
-}
-
-func (t *Terminal) GetWindow() *goncurses.Window {
-	//return goncurses.StdScr()
-	return t.Win
+	Backend     Backend
+	Colors      int
+	Pairs       map[[2]int16]int16
+	NextPair    int16
+	Styles      map[Style]TermStyle
+	FirstPair   int16
 }
 
+// clears screen; cover pairs are not on screen anymore and are freed
 func (t *Terminal) ClearScreen() {
-	w := t.GetWindow()
+	w := t.Backend
 	w.Clear()
 	w.Refresh()
+	t.FreePairs()
 }
 
-func GetScreenSize() (int, int) {
-	y, x := goncurses.StdScr().MaxYX()
-
-	return y, x
-}
 func (t *Terminal) MoveCursorTo(ypos int, xpos int) {
-	w := t.GetWindow()
+	w := t.Backend
 	w.Move(ypos, xpos)
 	w.Refresh()
 }
 
 func (t *Terminal) PrintMessage(s string, p ...interface{}) {
-	w := t.GetWindow()
+	w := t.Backend
 	y, x := w.CursorYX()
-	w.MovePrintf(y+1, 0, s, p...)
+	w.Move(y+1, 0)
+	w.Print(fmt.Sprintf(s, p...))
 	w.Move(y, x)
 	w.Refresh()
 }
 
 func (t *Terminal) Printf(s string, p ...interface{}) {
-	w := t.GetWindow()
-	w.Printf(s, p...)
+	w := t.Backend
+	w.Print(fmt.Sprintf(s, p...))
 	w.Refresh()
 }
 
 func (t *Terminal) Println(s string) {
-	w := t.GetWindow()
-	w.Println(s)
+	w := t.Backend
+	w.Print(s + "\n")
 	w.Refresh()
 }
 
+func (t *Terminal) ShowCursor(on bool) {
+	t.Backend.ShowCursor(on)
+}
+
+// restores terminal
+func (t *Terminal) End() {
+	t.Backend.End()
+}
+
+// KEY_RESIZE is returned after screens are resized
 func (t *Terminal) GetChar() goncurses.Key {
-	k := t.GetWindow().GetChar()
+	k := t.Backend.GetChar()
+	if k == goncurses.KEY_RESIZE {
+		t.Resize()
+	}
 	return k
 }
 
+// ncurses catches SIGWINCH and sends KEY_RESIZE: screen size is read again,
+// contexts of stacked screens are laid out for new size and top screen is redrawn
+func (t *Terminal) Resize() {
+	t.Lines, t.Cols = t.Backend.Size()
+	w := t.Backend
+
+	for _, ctx := range t.ScreenStack {
+		ctx.LogicLines = t.Lines
+		ctx.LogicCols = t.Cols
+		ctx.Scroll = 0
+		if ctx.CursorLine >= ctx.LogicLines {
+			ctx.CursorLine = ctx.LogicLines - 1
+		}
+		if ctx.CursorCol >= ctx.LogicCols {
+			ctx.CursorCol = ctx.LogicCols - 1
+		}
+		ctx.Resized = true
+	}
+
+	ctx := t.CurrentContext()
+	if ctx == nil {
+		w.Resize(t.Lines, t.Cols)
+		return
+	}
+	w.Resize(ctx.LogicLines*ctx.LineSize, t.Cols*ctx.ColSize)
+	ctx.Resized = false
+	ctx.CurrentScreen.OnRefresh(t.Lines, t.Cols)
+}
+
 func (t *Terminal) ScrollScr(n int) {
 
 	ctx := t.ScreenStack[len(t.ScreenStack)-1]
@@ -117,7 +159,7 @@ func (t *Terminal) ScrollScr(n int) {
 		}
 
 		n = ctx.Scroll - scroll
-		t.GetWindow().Scroll(n)
+		t.Backend.Scroll(n)
 		t.CurrentContext().CurrentScreen.OnScroll(n)
 	}
 }
@@ -127,8 +169,8 @@ func (t *Terminal) NewScreen(scr Screen) {
 	if t.CurrentContext() != nil {
 		t.SaveCursorPos()
 	}
-	ctx := &ScreenContext{scr, 0, 1, 1, t.Lines, t.Cols, false, 0, 0}
-	w := t.GetWindow()
+	ctx := &ScreenContext{scr, 0, 1, 1, t.Lines, t.Cols, false, 0, 0, false}
+	w := t.Backend
 	// add new context
 	t.ScreenStack = append(t.ScreenStack, ctx)
 	// init screen
@@ -144,7 +186,12 @@ func (t *Terminal) NewScreen(scr Screen) {
 	ctx = t.CurrentContext()
 	if ctx != nil {
 		w.Resize(ctx.LogicLines*ctx.LineSize, t.Cols*ctx.ColSize)
-		ctx.CurrentScreen.OnRefresh(0, 0)
+		if ctx.Resized {
+			ctx.Resized = false
+			ctx.CurrentScreen.OnRefresh(t.Lines, t.Cols)
+		} else {
+			ctx.CurrentScreen.OnRefresh(0, 0)
+		}
 		t.MoveCursorTo(ctx.CursorLine, ctx.CursorCol)
 	}
 }
@@ -163,7 +210,30 @@ func (t *Terminal) BeginRead() {
 	}
 	ctx.Reading = true
 	for ctx.Reading {
-		ctx.CurrentScreen.OnKey(t.GetChar())
+		key := t.GetChar()
+		if IsHelpKey(ctx.CurrentScreen, key) {
+			// key closing help is not read by screen
+			key = t.ShowHelp(ScreenActions(ctx.CurrentScreen))
+			ctx.CurrentScreen.OnRefresh(0, 0)
+			if key != KeyEOF {
+				continue
+			}
+		}
+		if key == KeyEOF {
+			// input closed: every screen ends
+			for _, c := range t.ScreenStack {
+				c.Reading = false
+			}
+			return
+		}
+		if key == goncurses.KEY_RESIZE {
+			continue
+		}
+		if key == goncurses.KEY_MOUSE {
+			t.ReadMouse()
+			continue
+		}
+		ctx.CurrentScreen.OnKey(key)
 	}
 }
 
@@ -207,7 +277,7 @@ func (t *Terminal) MoveNextLine() {
 		} else if ctx.CursorLine+ctx.Scroll+1 < ctx.LogicLines {
 
 			ctx.Scroll += 1
-			t.GetWindow().Scroll(1)
+			t.Backend.Scroll(1)
 			ctx.CurrentScreen.OnScroll(1)
 		}
 	}
@@ -224,7 +294,7 @@ func (t *Terminal) MovePrevLine() {
 		t.MoveCursorTo(ctx.CursorLine*ctx.LineSize, ctx.CursorCol*ctx.ColSize)
 	} else if ctx.Scroll-1 > -1 {
 		ctx.Scroll -= 1
-		t.GetWindow().Scroll(-1)
+		t.Backend.Scroll(-1)
 		ctx.CurrentScreen.OnScroll(-1)
 	}
 }
@@ -251,30 +321,40 @@ func (t *Terminal) CursorAddress(line, col int) {
 }
 
 func (t *Terminal) SaveCursorPos() (int, int) {
-	y, x := t.GetWindow().CursorYX()
+	y, x := t.Backend.CursorYX()
 	ctx := t.CurrentContext()
 	ctx.CursorLine = y / ctx.LineSize
 	ctx.CursorCol = x / ctx.ColSize
 	return y, x
 }
 
+// text in selection style if on
 func (t *Terminal) Highlight(line, col int, text string, on bool) {
 
-	w := t.GetWindow()
-	ctx := t.CurrentContext()
 	if on {
-		w.AttrOn(goncurses.A_REVERSE)
-	} else {
-		w.AttrOff(goncurses.A_REVERSE)
+		t.PrintStyle(line, col, text, StyleSelection)
+		return
 	}
-	w.MovePrint(line*ctx.LineSize, col*ctx.ColSize, text)
-	w.AttrOff(goncurses.A_REVERSE)
+	w := t.Backend
+	ctx := t.CurrentContext()
+	w.Move(line*ctx.LineSize, col*ctx.ColSize)
+	w.Print(text)
+}
+
+func (t *Terminal) PrintAttr(line, col int, text string, attr goncurses.Char) {
+
+	w := t.Backend
+	ctx := t.CurrentContext()
+	w.AttrOn(attr)
+	w.Move(line*ctx.LineSize, col*ctx.ColSize)
+	w.Print(text)
+	w.AttrOff(attr)
+	w.Refresh()
 }
 
 func TerminalLoop() {
 
 	tty := NewTerminal()
 	tty.NewScreen(&MenuScreen{})
-	goncurses.CBreak(false)
-	goncurses.End()
+	tty.End()
 }
@@ -0,0 +1,258 @@
+package console
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bookstore-go/config"
+	"github.com/rthornton128/goncurses"
+)
+
+// named style of text drawn by screens
+type Style string
+
+const (
+	StyleTitle     Style = "title"
+	StyleLabel     Style = "label"
+	StyleSelection Style = "selection"
+	StyleStatus    Style = "status"
+	StyleError     Style = "error"
+	StyleDim       Style = "dim"
+)
+
+var AllStyles = []Style{StyleTitle, StyleLabel, StyleSelection, StyleStatus, StyleError, StyleDim}
+
+// terminal default color, needs use_default_colors
+const ColorDefault int16 = -1
+
+// colors of style, attributes are added to colors
+type StyleDef struct {
+	Fg   int16
+	Bg   int16
+	Attr goncurses.Char
+}
+
+// attributes of styles on terminals without colors
+var MonoStyles = map[Style]goncurses.Char{
+	StyleTitle:     goncurses.A_BOLD,
+	StyleLabel:     goncurses.A_NORMAL,
+	StyleSelection: goncurses.A_REVERSE,
+	StyleStatus:    goncurses.A_REVERSE,
+	StyleError:     goncurses.A_REVERSE | goncurses.A_BOLD,
+	StyleDim:       goncurses.A_DIM,
+}
+
+var Themes = map[string]map[Style]StyleDef{
+	"dark": {
+		StyleTitle:     {goncurses.C_YELLOW, ColorDefault, goncurses.A_BOLD},
+		StyleLabel:     {goncurses.C_CYAN, ColorDefault, goncurses.A_NORMAL},
+		StyleSelection: {goncurses.C_BLACK, goncurses.C_CYAN, goncurses.A_NORMAL},
+		StyleStatus:    {goncurses.C_WHITE, goncurses.C_BLUE, goncurses.A_NORMAL},
+		StyleError:     {goncurses.C_WHITE, goncurses.C_RED, goncurses.A_BOLD},
+		StyleDim:       {ColorDefault, ColorDefault, goncurses.A_DIM},
+	},
+	"light": {
+		StyleTitle:     {goncurses.C_BLUE, ColorDefault, goncurses.A_BOLD},
+		StyleLabel:     {goncurses.C_MAGENTA, ColorDefault, goncurses.A_NORMAL},
+		StyleSelection: {goncurses.C_WHITE, goncurses.C_BLUE, goncurses.A_NORMAL},
+		StyleStatus:    {goncurses.C_WHITE, goncurses.C_BLACK, goncurses.A_NORMAL},
+		StyleError:     {goncurses.C_WHITE, goncurses.C_RED, goncurses.A_BOLD},
+		StyleDim:       {ColorDefault, ColorDefault, goncurses.A_DIM},
+	},
+	"mono": {},
+}
+
+var colorNames = map[string]int16{
+	"default": ColorDefault,
+	"black":   goncurses.C_BLACK,
+	"red":     goncurses.C_RED,
+	"green":   goncurses.C_GREEN,
+	"yellow":  goncurses.C_YELLOW,
+	"blue":    goncurses.C_BLUE,
+	"magenta": goncurses.C_MAGENTA,
+	"cyan":    goncurses.C_CYAN,
+	"white":   goncurses.C_WHITE,
+}
+
+var attrNames = map[string]goncurses.Char{
+	"normal":    goncurses.A_NORMAL,
+	"bold":      goncurses.A_BOLD,
+	"dim":       goncurses.A_DIM,
+	"underline": goncurses.A_UNDERLINE,
+	"reverse":   goncurses.A_REVERSE,
+	"blink":     goncurses.A_BLINK,
+	"italic":    A_ITALIC,
+}
+
+// color name or number of 256 colors palette
+func ParseColor(name string) (int16, error) {
+	if color, ok := colorNames[strings.ToLower(name)]; ok {
+		return color, nil
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil || n < 0 || n > 255 {
+		return 0, fmt.Errorf("Unknown color '%s'", name)
+	}
+	return int16(n), nil
+}
+
+func ParseAttr(names []string) (goncurses.Char, error) {
+	attr := goncurses.Char(goncurses.A_NORMAL)
+	for _, name := range names {
+		a, ok := attrNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("Unknown attribute '%s'", name)
+		}
+		attr |= a
+	}
+	return attr, nil
+}
+
+// styles of theme; Mono themes use MonoStyles
+type Theme struct {
+	Name   string
+	Mono   bool
+	Styles map[Style]StyleDef
+}
+
+// active theme, used by terminals created after it is set
+var CurrentTheme = DefaultTheme()
+
+func DefaultTheme() *Theme {
+	theme, _ := NewTheme("dark")
+	return theme
+}
+
+func NewTheme(name string) (*Theme, error) {
+	styles, ok := Themes[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown theme '%s'", name)
+	}
+	theme := &Theme{Name: name, Mono: name == "mono", Styles: make(map[Style]StyleDef)}
+	for style, def := range styles {
+		theme.Styles[style] = def
+	}
+	return theme, nil
+}
+
+// changes styles of theme; colors and attributes not set are kept
+func (theme *Theme) Apply(styles map[string]config.StyleConfig) error {
+	for name, conf := range styles {
+		style := Style(name)
+		def, ok := theme.Styles[style]
+		if _, known := MonoStyles[style]; !known {
+			return fmt.Errorf("Unknown style '%s'", name)
+		}
+		if !ok {
+			def = StyleDef{ColorDefault, ColorDefault, MonoStyles[style]}
+		}
+		var err error
+		if len(conf.Fg) > 0 {
+			if def.Fg, err = ParseColor(conf.Fg); err != nil {
+				return fmt.Errorf("Style '%s': %v", name, err)
+			}
+		}
+		if len(conf.Bg) > 0 {
+			if def.Bg, err = ParseColor(conf.Bg); err != nil {
+				return fmt.Errorf("Style '%s': %v", name, err)
+			}
+		}
+		if conf.Attr != nil {
+			if def.Attr, err = ParseAttr(conf.Attr); err != nil {
+				return fmt.Errorf("Style '%s': %v", name, err)
+			}
+		}
+		theme.Styles[style] = def
+	}
+	return nil
+}
+
+// theme of [theme] configuration section: named theme ("dark" if not set), styles of theme file, then styles of section
+func LoadThemeConfig(conf config.ThemeConfig) (*Theme, error) {
+	var file config.ThemeConfig
+	if len(conf.File) > 0 {
+		var err error
+		file, err = config.LoadThemeFile(conf.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	name := conf.Name
+	if len(name) == 0 {
+		name = file.Name
+	}
+	if len(name) == 0 {
+		name = "dark"
+	}
+	theme, err := NewTheme(name)
+	if err != nil {
+		return nil, err
+	}
+	err = theme.Apply(file.Styles)
+	if err != nil {
+		return nil, err
+	}
+	err = theme.Apply(conf.Styles)
+	if err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
+
+// sets active theme from [theme] configuration section
+func LoadTheme(conf config.ThemeConfig) error {
+	theme, err := LoadThemeConfig(conf)
+	if err != nil {
+		return err
+	}
+	CurrentTheme = theme
+	return nil
+}
+
+// ----------- TERMINAL STYLES -----------
+
+// attributes and color pair of style on terminal, pair 0 for no colors
+type TermStyle struct {
+	Attr goncurses.Char
+	Pair int16
+}
+
+// resolves styles of theme for terminal colors: terminals with less than 8 colors
+// use monochrome attributes, as do styles with colors terminal does not have
+func (t *Terminal) SetTheme(theme *Theme) {
+	t.Styles = make(map[Style]TermStyle)
+	t.Pairs = nil
+	t.FirstPair = 1
+	t.NextPair = 1
+	for _, style := range AllStyles {
+		def, ok := theme.Styles[style]
+		if theme.Mono || !ok || t.Colors < 8 || def.Fg >= int16(t.Colors) || def.Bg >= int16(t.Colors) {
+			t.Styles[style] = TermStyle{MonoStyles[style], 0}
+		} else if def.Fg == ColorDefault && def.Bg == ColorDefault {
+			t.Styles[style] = TermStyle{def.Attr, 0}
+		} else {
+			t.Styles[style] = TermStyle{def.Attr, t.ColorPair(def.Fg, def.Bg)}
+		}
+	}
+	// pairs of styles are not recycled by ColorPair
+	t.FirstPair = t.NextPair
+}
+
+func (t *Terminal) PrintStyle(line, col int, text string, style Style) {
+	w := t.Backend
+	ctx := t.CurrentContext()
+	s := t.Styles[style]
+	w.AttrOn(s.Attr)
+	if s.Pair > 0 {
+		w.ColorOn(s.Pair)
+	}
+	w.Move(line*ctx.LineSize, col*ctx.ColSize)
+	w.Print(text)
+	if s.Pair > 0 {
+		w.ColorOff(s.Pair)
+	}
+	w.AttrOff(s.Attr)
+	w.Refresh()
+}
@@ -0,0 +1,168 @@
+package console_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/config"
+	"github.com/bookstore-go/console"
+	"github.com/rthornton128/goncurses"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		name  string
+		color int16
+	}{
+		{"default", console.ColorDefault},
+		{"Red", goncurses.C_RED},
+		{"cyan", goncurses.C_CYAN},
+		{"208", 208},
+	}
+	for _, test := range tests {
+		color, err := console.ParseColor(test.name)
+		if err != nil || color != test.color {
+			t.Fatalf("Color '%s' should be %d (got %d, %v)\n", test.name, test.color, color, err)
+		}
+	}
+	for _, name := range []string{"", "pink", "256", "-2"} {
+		if _, err := console.ParseColor(name); err == nil {
+			t.Fatalf("Color '%s' should not parse\n", name)
+		}
+	}
+
+	attr, err := console.ParseAttr([]string{"bold", "Underline"})
+	if err != nil || attr != goncurses.A_BOLD|goncurses.A_UNDERLINE {
+		t.Fatalf("Bold underline expected (got %d, %v)\n", attr, err)
+	}
+	if _, err := console.ParseAttr([]string{"shiny"}); err == nil {
+		t.Fatalf("Unknown attribute should not parse\n")
+	}
+}
+
+func TestLoadThemeConfig(t *testing.T) {
+	theme, err := console.LoadThemeConfig(config.ThemeConfig{})
+	if err != nil || theme.Name != "dark" {
+		t.Fatalf("Dark theme expected by default (got %v, %v)\n", theme, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "theme.toml")
+	err = os.WriteFile(file, []byte("name = \"light\"\n[styles.title]\nfg = \"green\"\nattr = [\"underline\"]\n[styles.label]\nfg = \"red\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme, err = console.LoadThemeConfig(config.ThemeConfig{File: file, Styles: map[string]config.StyleConfig{
+		"label": {Fg: "yellow", Bg: "236"},
+	}})
+	if err != nil {
+		t.Fatalf("Theme should load (got %v)\n", err)
+	}
+	title := theme.Styles[console.StyleTitle]
+	if theme.Name != "light" || title.Fg != goncurses.C_GREEN || title.Bg != console.ColorDefault || title.Attr != goncurses.A_UNDERLINE {
+		t.Fatalf("Title of theme file expected (got %s %v)\n", theme.Name, title)
+	}
+	// styles of configuration are applied after theme file
+	if label := theme.Styles[console.StyleLabel]; label.Fg != goncurses.C_YELLOW || label.Bg != 236 {
+		t.Fatalf("Label of configuration expected (got %v)\n", label)
+	}
+
+	bad := []config.ThemeConfig{
+		{Name: "solarized"},
+		{File: filepath.Join(t.TempDir(), "missing.toml")},
+		{Styles: map[string]config.StyleConfig{"shadow": {Fg: "red"}}},
+		{Styles: map[string]config.StyleConfig{"title": {Bg: "pink"}}},
+	}
+	for _, conf := range bad {
+		if _, err := console.LoadThemeConfig(conf); err == nil {
+			t.Fatalf("Theme %v should not load\n", conf)
+		}
+	}
+}
+
+func TestTerminalTheme(t *testing.T) {
+	theme, _ := console.NewTheme("dark")
+	theme.Styles[console.StyleDim] = console.StyleDef{Fg: 200, Bg: console.ColorDefault}
+
+	// colors: style pairs are initialized with theme colors
+	vs := console.NewVirtualScreen(24, 80)
+	vs.NbColors = 8
+	tty := console.NewBackendTerminal(vs)
+	tty.SetTheme(theme)
+
+	selection := tty.Styles[console.StyleSelection]
+	if selection.Pair == 0 || vs.Pairs[selection.Pair] != [2]int16{goncurses.C_BLACK, goncurses.C_CYAN} {
+		t.Fatalf("Selection pair should be black on cyan (got %v)\n", selection)
+	}
+	// color 200 is not available on 8 colors terminal
+	if dim := tty.Styles[console.StyleDim]; dim.Pair != 0 || dim.Attr != goncurses.A_DIM {
+		t.Fatalf("Monochrome dim expected (got %v)\n", dim)
+	}
+
+	// pairs of styles are not recycled by cover colors
+	for i := int16(0); i < 300; i += 1 {
+		tty.ColorPair(i%256, 255-i%256)
+	}
+	if vs.Pairs[selection.Pair] != [2]int16{goncurses.C_BLACK, goncurses.C_CYAN} {
+		t.Fatalf("Selection pair should be kept (got %v)\n", vs.Pairs[selection.Pair])
+	}
+
+	// no colors: monochrome attributes
+	vs = console.NewVirtualScreen(24, 80)
+	tty = console.NewBackendTerminal(vs)
+	tty.SetTheme(theme)
+	if status := tty.Styles[console.StyleStatus]; status.Pair != 0 || status.Attr != goncurses.A_REVERSE {
+		t.Fatalf("Reverse status expected without colors (got %v)\n", status)
+	}
+}
+
+// screen running test function while it is on top
+type runScreen struct {
+	Tty  *console.Terminal
+	Test func(tty *console.Terminal)
+}
+
+func (s *runScreen) Init(tty *console.Terminal, ctx *console.ScreenContext) {
+	s.Tty = tty
+}
+
+func (s *runScreen) Run() {
+	s.Test(s.Tty)
+}
+
+func (s *runScreen) OnScroll(y int)            {}
+func (s *runScreen) OnKey(k goncurses.Key)     {}
+func (s *runScreen) OnRefresh(lines, cols int) {}
+
+func TestStatusBarStyle(t *testing.T) {
+	vs := console.NewVirtualScreen(24, 80)
+	vs.NbColors = 8
+	tty := console.NewBackendTerminal(vs)
+
+	tty.NewScreen(&runScreen{Test: func(tty *console.Terminal) {
+		bar := &console.StatusBar{Help: "help"}
+		for _, test := range []struct {
+			err   error
+			style console.Style
+		}{
+			{nil, console.StyleStatus},
+			{errors.New("failed"), console.StyleError},
+		} {
+			bar.Err = test.err
+			bar.Draw(tty)
+			want := tty.Styles[test.style]
+			if cell := vs.Cells[23][0]; cell.Attr != want.Attr || cell.Pair != want.Pair {
+				t.Fatalf("Style %s expected on status line (got %v)\n", test.style, cell)
+			}
+		}
+		if line := vs.LineText(23); !strings.HasPrefix(line, "failed ") || !strings.HasSuffix(line, " db:off") {
+			t.Fatalf("Error should be shown (got '%s')\n", line)
+		}
+		bar.Clear()
+		if bar.Text() != "help" {
+			t.Fatalf("Help should be shown after clear (got '%s')\n", bar.Text())
+		}
+	}})
+}
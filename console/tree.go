@@ -0,0 +1,334 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bookstore-go/utils"
+	"github.com/rthornton128/goncurses"
+)
+
+// node of collapsible tree; Key identifies node across reloads, Value is the object shown
+type TreeNode struct {
+	Key      string
+	Label    string
+	Count    uint
+	Children []*TreeNode
+	Value    interface{}
+}
+
+// visible node; Parent is row of parent node, -1 for roots
+type TreeRow struct {
+	Node   *TreeNode
+	Depth  int
+	Parent int
+}
+
+// visible rows of tree: children of expanded nodes are shown
+func FlattenTree(roots []*TreeNode, expanded map[string]bool) []TreeRow {
+	var rows []TreeRow
+	var add func(nodes []*TreeNode, depth, parent int)
+	add = func(nodes []*TreeNode, depth, parent int) {
+		for _, node := range nodes {
+			rows = append(rows, TreeRow{node, depth, parent})
+			if expanded[node.Key] {
+				add(node.Children, depth+1, len(rows)-1)
+			}
+		}
+	}
+	add(roots, 0, -1)
+	return rows
+}
+
+// rows of matching nodes and their ancestors, shown expanded; matches are indexes of rows of matching nodes
+func FilterTree(roots []*TreeNode, match func(node *TreeNode) bool) ([]TreeRow, []int) {
+	var rows []TreeRow
+	var matches []int
+	var add func(nodes []*TreeNode, depth, parent int)
+	add = func(nodes []*TreeNode, depth, parent int) {
+		for _, node := range nodes {
+			index := len(rows)
+			rows = append(rows, TreeRow{node, depth, parent})
+			found := match(node)
+			if found {
+				matches = append(matches, index)
+			}
+			add(node.Children, depth+1, index)
+			if !found && len(rows) == index+1 {
+				// nothing matches in subtree
+				rows = rows[:index]
+			}
+		}
+	}
+	add(roots, 0, -1)
+	return rows, matches
+}
+
+func TreeLine(row TreeRow, expanded bool) string {
+	marker := "  "
+	if len(row.Node.Children) > 0 {
+		marker = "+ "
+		if expanded {
+			marker = "- "
+		}
+	}
+	return fmt.Sprintf("%s%s%s (%d)", strings.Repeat("  ", row.Depth), marker, row.Node.Label, row.Node.Count)
+}
+
+func SubjectTreeNodes(subjects []*utils.SubjectNode) []*TreeNode {
+	var nodes []*TreeNode
+	for _, sub := range subjects {
+		nodes = append(nodes, &TreeNode{fmt.Sprintf("subject:%d", sub.Id), sub.Name, sub.TotalBooks, SubjectTreeNodes(sub.Children), sub})
+	}
+	return nodes
+}
+
+// tags are children of a "Tags" node after subjects
+func TagsTreeNode(tags []utils.Tag) *TreeNode {
+	node := &TreeNode{Key: "tags", Label: "Tags", Count: uint(len(tags))}
+	for _, tag := range tags {
+		node.Children = append(node.Children, &TreeNode{"tag:" + tag.Name, tag.Name, tag.NbBooks, nil, tag})
+	}
+	return node
+}
+
+// subjects tree and tags; subject moved with x (cut) then p (paste under selected subject) or P (paste as root subject).
+// '/' filters tree: matching nodes are shown with their ancestors
+type SubjectsScreen struct {
+	Tty      *Terminal
+	Roots    []*TreeNode
+	Rows     []TreeRow
+	Expanded map[string]bool
+	List     *ListView
+	Cut      *utils.SubjectNode
+	Status   *StatusBar
+	Filter   *Filter
+	Matches  []int
+}
+
+func (subscr *SubjectsScreen) Init(tty *Terminal, ctx *ScreenContext) {
+	subscr.Tty = tty
+	subscr.Expanded = make(map[string]bool)
+	subscr.Filter = NewFilter()
+	subscr.Status = &StatusBar{Help: Keys.Hints(ActionHelp, ActionExpand, ActionCollapse, ActionOpen, ActionFilter, ActionCut, ActionPaste, ActionPasteRoot, ActionQuit)}
+	subscr.List = NewListView(0, subscr.PageSize(), tty.Cols, subscr.RowLine)
+	subscr.Load()
+	tty.ClearScreen()
+}
+
+func (subscr *SubjectsScreen) Load() {
+	subjects, err := utils.GetSubjectTree()
+	if err != nil {
+		subscr.Status.Err = err
+	}
+	subscr.Roots = SubjectTreeNodes(subjects)
+
+	tags, err := utils.GetTags()
+	if err != nil {
+		subscr.Status.Err = err
+	}
+	if len(tags) > 0 {
+		subscr.Roots = append(subscr.Roots, TagsTreeNode(tags))
+	}
+	subscr.Flatten()
+}
+
+// rebuilds visible rows after expanding, loading or filtering
+func (subscr *SubjectsScreen) Flatten() {
+	if subscr.Filter.Active() {
+		subscr.Rows, subscr.Matches = FilterTree(subscr.Roots, func(node *TreeNode) bool {
+			return subscr.Filter.Match(node.Label)
+		})
+	} else {
+		subscr.Rows = FlattenTree(subscr.Roots, subscr.Expanded)
+		subscr.Matches = nil
+	}
+	subscr.List.SetCount(len(subscr.Rows))
+}
+
+// row of node with key, -1 if not visible
+func (subscr *SubjectsScreen) RowOf(key string) int {
+	for i, row := range subscr.Rows {
+		if row.Node.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// filter changed: selected node stays selected if visible, first match is selected otherwise
+func (subscr *SubjectsScreen) ApplyFilter() {
+	key := ""
+	if selected := subscr.List.Selected; selected < len(subscr.Rows) {
+		key = subscr.Rows[selected].Node.Key
+		// ancestors are expanded to keep node visible without filter
+		for parent := subscr.Rows[selected].Parent; parent >= 0; parent = subscr.Rows[parent].Parent {
+			subscr.Expanded[subscr.Rows[parent].Node.Key] = true
+		}
+	}
+	subscr.Flatten()
+
+	index := subscr.RowOf(key)
+	if index < 0 || (subscr.Filter.Active() && !subscr.Filter.Match(subscr.Rows[index].Node.Label)) {
+		index = NextMatch(subscr.Matches, -1, 1)
+	}
+	subscr.List.Select(index)
+}
+
+func (subscr *SubjectsScreen) Run() {
+	subscr.OnRefresh(0, 0)
+	subscr.Tty.BeginRead()
+}
+
+// actions of subjects tree
+var SubjectsActions = append([]Action{ActionExpand, ActionCollapse, ActionOpen, ActionFilter, ActionNextMatch, ActionPrevMatch,
+	ActionCut, ActionPaste, ActionPasteRoot, ActionQuit}, ListActions...)
+
+func (subscr *SubjectsScreen) HelpActions() []Action {
+	return SubjectsActions
+}
+
+func (subscr *SubjectsScreen) Typing() bool {
+	return subscr.Filter.Editing
+}
+
+func (subscr *SubjectsScreen) OnScroll(y int) {
+	// list is scrolled by list view
+}
+
+// number of tree lines on screen, first line is header, last line is status line
+func (subscr *SubjectsScreen) PageSize() int {
+	size := subscr.Tty.Lines - 2
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// node is shown expanded when next row is its child
+func (subscr *SubjectsScreen) RowLine(index int) string {
+	expanded := index+1 < len(subscr.Rows) && subscr.Rows[index+1].Parent == index
+	return TreeLine(subscr.Rows[index], expanded)
+}
+
+func (subscr *SubjectsScreen) Crumb() string {
+	return "Subjects"
+}
+
+func (subscr *SubjectsScreen) OnRefresh(Lines, Cols int) {
+	tty := subscr.Tty
+	if Lines > 0 {
+		subscr.List.Resize(subscr.PageSize(), Cols)
+	}
+	tty.ClearScreen()
+	tty.DrawHeader()
+	subscr.List.Draw(tty, 1, 0)
+	subscr.Status.Draw(tty)
+}
+
+func (subscr *SubjectsScreen) OnKey(key goncurses.Key) {
+	if subscr.Filter.OnKey(key) {
+		subscr.ApplyFilter()
+		subscr.Status.Message = subscr.Filter.Text(len(subscr.Matches))
+		subscr.OnRefresh(0, 0)
+		return
+	}
+	subscr.Status.Clear()
+	subscr.Status.Message = subscr.Filter.Text(len(subscr.Matches))
+
+	var row TreeRow
+	var value interface{}
+	selected := subscr.List.Selected
+	if selected < len(subscr.Rows) {
+		row = subscr.Rows[selected]
+		value = row.Node.Value
+	}
+
+	switch Keys.Action(key, SubjectsActions...) {
+	case ActionQuit:
+		subscr.Tty.EndRead()
+		return
+	case ActionExpand:
+		if row.Node != nil && len(row.Node.Children) > 0 {
+			if subscr.Expanded[row.Node.Key] {
+				selected += 1
+			} else {
+				subscr.Expanded[row.Node.Key] = true
+			}
+		}
+	case ActionCollapse:
+		if row.Node != nil && subscr.Expanded[row.Node.Key] {
+			subscr.Expanded[row.Node.Key] = false
+		} else if row.Parent >= 0 {
+			selected = row.Parent
+		}
+	case ActionOpen:
+		subscr.OpenBooks(row.Node)
+	case ActionNextMatch:
+		selected = NextMatch(subscr.Matches, selected, 1)
+	case ActionPrevMatch:
+		selected = NextMatch(subscr.Matches, selected, -1)
+	case ActionCut:
+		if sub, ok := value.(*utils.SubjectNode); ok {
+			subscr.Cut = sub
+			subscr.Status.Message = fmt.Sprintf("'%s' cut: %s to paste under selected subject, %s to make it a root subject",
+				sub.Name, Keys.Names(ActionPaste), Keys.Names(ActionPasteRoot))
+		}
+	case ActionPaste:
+		if sub, ok := value.(*utils.SubjectNode); ok && subscr.Cut != nil {
+			if subscr.Cut.IsAncestorOf(sub) {
+				subscr.Status.Err = fmt.Errorf("Cannot move '%s' under itself", subscr.Cut.Name)
+			} else {
+				subscr.MoveCut(sub.Id)
+				subscr.Expanded[row.Node.Key] = true
+			}
+		}
+	case ActionPasteRoot:
+		if subscr.Cut != nil {
+			subscr.MoveCut(0)
+		}
+	default:
+		subscr.List.OnKey(key)
+		selected = subscr.List.Selected
+	}
+
+	subscr.Flatten()
+	subscr.List.Select(selected)
+	subscr.OnRefresh(0, 0)
+}
+
+// double-click opens books of node
+func (subscr *SubjectsScreen) OnMouse(ev MouseEvent) {
+	if subscr.List.OnMouse(ev, 1, 0) && subscr.List.Selected < len(subscr.Rows) {
+		subscr.OpenBooks(subscr.Rows[subscr.List.Selected].Node)
+	}
+	subscr.OnRefresh(0, 0)
+}
+
+// sets parent of cut subject and reloads tree
+func (subscr *SubjectsScreen) MoveCut(parentId uint) {
+	err := utils.SetSubjectParent(subscr.Cut.Id, parentId)
+	if err != nil {
+		subscr.Status.Err = err
+		return
+	}
+	subscr.Cut = nil
+	subscr.Load()
+}
+
+// books of subject and its descendants, or books of tag
+func (subscr *SubjectsScreen) OpenBooks(node *TreeNode) {
+	if node == nil {
+		return
+	}
+	if node.Count == 0 {
+		subscr.Status.Message = fmt.Sprintf("No book in '%s'", node.Label)
+		return
+	}
+	switch value := node.Value.(type) {
+	case *utils.SubjectNode:
+		subscr.Tty.NewScreen(&SubjectBooks{Sub: &value.Subject, SubjectIds: value.SubtreeIds()})
+	case utils.Tag:
+		subscr.Tty.NewScreen(&SubjectBooks{Tag: value.Name})
+	}
+}
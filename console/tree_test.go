@@ -0,0 +1,72 @@
+package console_test
+
+import (
+	"testing"
+
+	"github.com/bookstore-go/console"
+	"github.com/bookstore-go/utils"
+)
+
+func TestFlattenTree(t *testing.T) {
+	subjects := []utils.Subject{
+		{Id: 1, Name: "Programming"},
+		{Id: 2, Name: "Languages", ParentId: 1},
+		{Id: 3, Name: "Go", ParentId: 2},
+		{Id: 4, Name: "Databases"},
+	}
+	books := map[uint][]int{3: {10, 11}, 4: {12}}
+	roots := console.SubjectTreeNodes(utils.BuildSubjectTree(subjects, books))
+	roots = append(roots, console.TagsTreeNode([]utils.Tag{{Name: "concurrency", NbBooks: 2}}))
+
+	expanded := map[string]bool{}
+	rows := console.FlattenTree(roots, expanded)
+	if len(rows) != 3 || rows[0].Node.Label != "Databases" || rows[2].Node.Label != "Tags" {
+		t.Fatalf("3 collapsed roots expected (got %d)\n", len(rows))
+	}
+
+	expanded["subject:1"] = true
+	expanded["subject:2"] = true
+	rows = console.FlattenTree(roots, expanded)
+	if len(rows) != 5 || rows[3].Node.Label != "Go" || rows[3].Depth != 2 || rows[3].Parent != 2 {
+		t.Fatalf("Go should be visible under Languages (got %d rows)\n", len(rows))
+	}
+
+	if line := console.TreeLine(rows[1], true); line != "- Programming (2)" {
+		t.Fatalf("Line mismatch (got '%s')\n", line)
+	}
+	if line := console.TreeLine(rows[3], false); line != "      Go (2)" {
+		t.Fatalf("Line mismatch (got '%s')\n", line)
+	}
+}
+
+func TestFilterTree(t *testing.T) {
+	subjects := []utils.Subject{
+		{Id: 1, Name: "Programming"},
+		{Id: 2, Name: "Languages", ParentId: 1},
+		{Id: 3, Name: "Go", ParentId: 2},
+		{Id: 4, Name: "Algorithms", ParentId: 1},
+		{Id: 5, Name: "Databases"},
+	}
+	roots := console.SubjectTreeNodes(utils.BuildSubjectTree(subjects, nil))
+
+	rows, matches := console.FilterTree(roots, func(node *console.TreeNode) bool {
+		return console.FuzzyMatch("go", node.Label)
+	})
+	// Go with its ancestors, Algorithms matches too
+	if len(rows) != 4 || rows[0].Node.Label != "Programming" || rows[1].Node.Label != "Algorithms" || rows[3].Node.Label != "Go" {
+		t.Fatalf("Rows mismatch (got %d rows)\n", len(rows))
+	}
+	if rows[3].Parent != 2 || rows[2].Parent != 0 {
+		t.Fatalf("Parents mismatch (got %d, %d)\n", rows[3].Parent, rows[2].Parent)
+	}
+	if len(matches) != 2 || matches[0] != 1 || matches[1] != 3 {
+		t.Fatalf("Matches mismatch (got %v)\n", matches)
+	}
+
+	rows, matches = console.FilterTree(roots, func(node *console.TreeNode) bool {
+		return console.FuzzyMatch("xyz", node.Label)
+	})
+	if len(rows) != 0 || len(matches) != 0 {
+		t.Fatalf("No row expected (got %d)\n", len(rows))
+	}
+}
@@ -0,0 +1,273 @@
+package console
+
+import (
+	"strings"
+
+	"github.com/rthornton128/goncurses"
+)
+
+type VirtualCell struct {
+	Text string
+	Attr goncurses.Char
+	Pair int16
+}
+
+// in-memory terminal: keys are read from Keys, drawn text is kept in Cells and
+// Text of screen is saved in Shots each time a key is read.
+// When Keys is empty GetChar returns KeyEOF and screens end
+type VirtualScreen struct {
+	Lines    int
+	Cols     int
+	Cells    [][]VirtualCell
+	Line     int
+	Col      int
+	Attr     goncurses.Char
+	Pair     int16
+	CursorOn bool
+	NbColors int
+	Pairs    map[int16][2]int16
+	Keys     []goncurses.Key
+	Mouse    []MouseEvent
+	Shots    []string
+}
+
+func NewVirtualScreen(lines, cols int) *VirtualScreen {
+	vs := &VirtualScreen{Lines: lines, Cols: cols, Pairs: make(map[int16][2]int16)}
+	vs.Resize(lines, cols)
+	return vs
+}
+
+// keys read by screens
+func (vs *VirtualScreen) SendKeys(keys ...goncurses.Key) {
+	vs.Keys = append(vs.Keys, keys...)
+}
+
+// text typed as curses sends it, one key per byte
+func (vs *VirtualScreen) SendText(text string) {
+	for _, b := range []byte(text) {
+		vs.Keys = append(vs.Keys, goncurses.Key(b))
+	}
+}
+
+// mouse event at terminal line and column, read after KEY_MOUSE
+func (vs *VirtualScreen) SendMouse(action MouseAction, line, col int) {
+	vs.Keys = append(vs.Keys, goncurses.KEY_MOUSE)
+	vs.Mouse = append(vs.Mouse, MouseEvent{action, line, col})
+}
+
+// terminal resized by user: next key is KEY_RESIZE
+func (vs *VirtualScreen) SetSize(lines, cols int) {
+	vs.Lines = lines
+	vs.Cols = cols
+	vs.Keys = append([]goncurses.Key{goncurses.KEY_RESIZE}, vs.Keys...)
+}
+
+// text of line without trailing spaces
+func (vs *VirtualScreen) LineText(line int) string {
+	if line < 0 || line >= len(vs.Cells) {
+		return ""
+	}
+	var sb strings.Builder
+	for _, cell := range vs.Cells[line] {
+		sb.WriteString(cell.Text)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// lines of screen, trailing empty lines removed
+func (vs *VirtualScreen) Text() string {
+	lines := make([]string, len(vs.Cells))
+	for i := range vs.Cells {
+		lines[i] = vs.LineText(i)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+func (vs *VirtualScreen) AttrAt(line, col int) goncurses.Char {
+	if line < 0 || line >= len(vs.Cells) || col < 0 || col >= len(vs.Cells[line]) {
+		return 0
+	}
+	return vs.Cells[line][col].Attr
+}
+
+func (vs *VirtualScreen) emptyLine(cols int) []VirtualCell {
+	line := make([]VirtualCell, cols)
+	for i := range line {
+		line[i].Text = " "
+	}
+	return line
+}
+
+func (vs *VirtualScreen) Size() (int, int) {
+	return vs.Lines, vs.Cols
+}
+
+// content is kept, cut or padded with spaces
+func (vs *VirtualScreen) Resize(lines, cols int) {
+	cells := make([][]VirtualCell, lines)
+	for i := range cells {
+		cells[i] = vs.emptyLine(cols)
+		if i < len(vs.Cells) {
+			copy(cells[i], vs.Cells[i])
+		}
+	}
+	vs.Cells = cells
+	vs.Move(vs.Line, vs.Col)
+}
+
+func (vs *VirtualScreen) Clear() {
+	for i := range vs.Cells {
+		vs.Cells[i] = vs.emptyLine(len(vs.Cells[i]))
+	}
+	vs.Line, vs.Col = 0, 0
+}
+
+// cursor stays in window
+func (vs *VirtualScreen) Move(line, col int) {
+	lines, cols := vs.windowSize()
+	if line >= lines {
+		line = lines - 1
+	}
+	if col >= cols {
+		col = cols - 1
+	}
+	if line < 0 {
+		line = 0
+	}
+	if col < 0 {
+		col = 0
+	}
+	vs.Line, vs.Col = line, col
+}
+
+func (vs *VirtualScreen) windowSize() (int, int) {
+	if len(vs.Cells) == 0 {
+		return 0, 0
+	}
+	return len(vs.Cells), len(vs.Cells[0])
+}
+
+func (vs *VirtualScreen) CursorYX() (int, int) {
+	return vs.Line, vs.Col
+}
+
+// like curses: newline clears end of line, text wraps at last column and window scrolls at bottom
+func (vs *VirtualScreen) Print(text string) {
+	lines, cols := vs.windowSize()
+	if lines == 0 || cols == 0 {
+		return
+	}
+	for _, r := range text {
+		switch r {
+		case '\n':
+			for col := vs.Col; col < cols; col += 1 {
+				vs.Cells[vs.Line][col] = VirtualCell{" ", 0, 0}
+			}
+			vs.newLine()
+		case '\t':
+			for next := (vs.Col/8 + 1) * 8; vs.Col < next && vs.Col < cols; {
+				vs.put(" ")
+			}
+		default:
+			vs.put(string(r))
+		}
+	}
+}
+
+func (vs *VirtualScreen) put(text string) {
+	vs.Cells[vs.Line][vs.Col] = VirtualCell{text, vs.Attr, vs.Pair}
+	vs.Col += 1
+	if vs.Col >= len(vs.Cells[vs.Line]) {
+		vs.newLine()
+	}
+}
+
+func (vs *VirtualScreen) newLine() {
+	vs.Col = 0
+	vs.Line += 1
+	if vs.Line >= len(vs.Cells) {
+		vs.Scroll(1)
+		vs.Line = len(vs.Cells) - 1
+	}
+}
+
+func (vs *VirtualScreen) AttrOn(attr goncurses.Char) {
+	vs.Attr |= attr
+}
+
+func (vs *VirtualScreen) AttrOff(attr goncurses.Char) {
+	vs.Attr &^= attr
+}
+
+func (vs *VirtualScreen) ColorOn(pair int16) {
+	vs.Pair = pair
+}
+
+func (vs *VirtualScreen) ColorOff(pair int16) {
+	vs.Pair = 0
+}
+
+func (vs *VirtualScreen) InitPair(pair, fg, bg int16) {
+	vs.Pairs[pair] = [2]int16{fg, bg}
+}
+
+func (vs *VirtualScreen) Colors() int {
+	return vs.NbColors
+}
+
+func (vs *VirtualScreen) ColorPairs() int {
+	return 256
+}
+
+// positive n scrolls content up
+func (vs *VirtualScreen) Scroll(n int) {
+	lines, cols := vs.windowSize()
+	if lines == 0 {
+		return
+	}
+	for ; n > 0; n -= 1 {
+		vs.Cells = append(vs.Cells[1:], vs.emptyLine(cols))
+	}
+	for ; n < 0; n += 1 {
+		vs.Cells = append([][]VirtualCell{vs.emptyLine(cols)}, vs.Cells[:lines-1]...)
+	}
+}
+
+func (vs *VirtualScreen) ShowCursor(on bool) {
+	vs.CursorOn = on
+}
+
+// text of screen when last key was read
+func (vs *VirtualScreen) LastShot() string {
+	if len(vs.Shots) == 0 {
+		return ""
+	}
+	return vs.Shots[len(vs.Shots)-1]
+}
+
+func (vs *VirtualScreen) GetChar() goncurses.Key {
+	vs.Shots = append(vs.Shots, vs.Text())
+	if len(vs.Keys) == 0 {
+		return KeyEOF
+	}
+	key := vs.Keys[0]
+	vs.Keys = vs.Keys[1:]
+	return key
+}
+
+func (vs *VirtualScreen) GetMouse() (MouseEvent, bool) {
+	if len(vs.Mouse) == 0 {
+		return MouseEvent{}, false
+	}
+	ev := vs.Mouse[0]
+	vs.Mouse = vs.Mouse[1:]
+	return ev, true
+}
+
+func (vs *VirtualScreen) Refresh() {
+	// nothing to refresh
+}
+
+func (vs *VirtualScreen) End() {
+	// nothing to restore
+}
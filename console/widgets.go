@@ -0,0 +1,539 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rthornton128/goncurses"
+)
+
+// text cut or padded with spaces to width columns
+func FitText(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	text = CutText(text, width)
+	return text + strings.Repeat(" ", width-StringWidth(text))
+}
+
+// text cut to width columns; wide characters are not split
+func CutText(text string, width int) string {
+	col := 0
+	for i, r := range text {
+		col += RuneWidth(r)
+		if col > width {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func (t *Terminal) PrintAt(line, col int, text string) {
+	t.CursorAddress(line, col)
+	t.Printf("%s", text)
+}
+
+// ----------- LIST -----------
+
+// scrollable list of Count items shown Height lines high; Item returns text of item
+type ListView struct {
+	Count    int
+	Selected int
+	Top      int
+	Height   int
+	Width    int
+	Item     func(index int) string
+}
+
+func NewListView(count, height, width int, item func(index int) string) *ListView {
+	return &ListView{Count: count, Height: height, Width: width, Item: item}
+}
+
+// selects item index, scrolls to keep it visible
+func (l *ListView) Select(index int) {
+	if index >= l.Count {
+		index = l.Count - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	l.Selected = index
+	if l.Selected < l.Top {
+		l.Top = l.Selected
+	}
+	if l.Height > 0 && l.Selected >= l.Top+l.Height {
+		l.Top = l.Selected - l.Height + 1
+	}
+}
+
+// new size after terminal resize, selected item kept visible
+func (l *ListView) Resize(height, width int) {
+	l.Height = height
+	l.Width = width
+	if l.Top > 0 && l.Top+l.Height > l.Count {
+		l.Top = l.Count - l.Height
+		if l.Top < 0 {
+			l.Top = 0
+		}
+	}
+	l.Select(l.Selected)
+}
+
+// number of items changed
+func (l *ListView) SetCount(count int) {
+	l.Count = count
+	if l.Top > 0 && l.Top+l.Height > count {
+		l.Top = count - l.Height
+		if l.Top < 0 {
+			l.Top = 0
+		}
+	}
+	l.Select(l.Selected)
+}
+
+// navigation actions of lists and viewers
+var ListActions = []Action{ActionUp, ActionDown, ActionPageUp, ActionPageDown, ActionHome, ActionEnd}
+
+// moves selection; returns false if key is not a navigation key
+func (l *ListView) OnKey(key goncurses.Key) bool {
+	switch Keys.Action(key, ListActions...) {
+	case ActionUp:
+		l.Select(l.Selected - 1)
+	case ActionDown:
+		l.Select(l.Selected + 1)
+	case ActionPageUp:
+		l.Select(l.Selected - l.Height)
+	case ActionPageDown:
+		l.Select(l.Selected + l.Height)
+	case ActionHome:
+		l.Select(0)
+	case ActionEnd:
+		l.Select(l.Count - 1)
+	default:
+		return false
+	}
+	return true
+}
+
+// texts of visible items fitted to list width
+func (l *ListView) Visible() []string {
+	var lines []string
+	for i := l.Top; i < l.Count && i < l.Top+l.Height; i += 1 {
+		lines = append(lines, FitText(l.Item(i), l.Width))
+	}
+	return lines
+}
+
+func (l *ListView) Draw(tty *Terminal, line, col int) {
+	for i, text := range l.Visible() {
+		tty.Highlight(line+i, col, text, l.Top+i == l.Selected)
+	}
+}
+
+// ----------- GRID -----------
+
+// items in columns of ColWidth, filled column after column; Height lines are visible
+type GridView struct {
+	Count    int
+	Selected int
+	Top      int
+	Height   int
+	Cols     int
+	ColWidth int
+	Item     func(index int) string
+}
+
+func NewGridView(count, height, width, col_width int, item func(index int) string) *GridView {
+	cols := width / col_width
+	if cols < 1 {
+		cols = 1
+	}
+	return &GridView{Count: count, Height: height, Cols: cols, ColWidth: col_width, Item: item}
+}
+
+// lines of grid
+func (g *GridView) Rows() int {
+	return (g.Count + g.Cols - 1) / g.Cols
+}
+
+func (g *GridView) Select(index int) {
+	if index >= g.Count {
+		index = g.Count - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	g.Selected = index
+	rows := g.Rows()
+	if rows == 0 {
+		return
+	}
+	row := g.Selected % rows
+	if row < g.Top {
+		g.Top = row
+	}
+	if g.Height > 0 && row >= g.Top+g.Height {
+		g.Top = row - g.Height + 1
+	}
+}
+
+// columns are recomputed from width, selected item kept visible
+func (g *GridView) Resize(height, width int) {
+	g.Height = height
+	g.Cols = width / g.ColWidth
+	if g.Cols < 1 {
+		g.Cols = 1
+	}
+	g.Top = 0
+	g.Select(g.Selected)
+}
+
+// navigation actions of grids
+var GridActions = append([]Action{ActionLeft, ActionRight}, ListActions...)
+
+func (g *GridView) OnKey(key goncurses.Key) bool {
+	rows := g.Rows()
+	switch Keys.Action(key, GridActions...) {
+	case ActionUp:
+		g.Select(g.Selected - 1)
+	case ActionDown:
+		g.Select(g.Selected + 1)
+	case ActionLeft:
+		if g.Selected-rows >= 0 {
+			g.Select(g.Selected - rows)
+		}
+	case ActionRight:
+		if g.Selected+rows < g.Count {
+			g.Select(g.Selected + rows)
+		}
+	case ActionPageUp:
+		g.Select(g.Selected - g.Height)
+	case ActionPageDown:
+		g.Select(g.Selected + g.Height)
+	case ActionHome:
+		g.Select(0)
+	case ActionEnd:
+		g.Select(g.Count - 1)
+	default:
+		return false
+	}
+	return true
+}
+
+// index of item at visible line and column of grid, -1 if none
+func (g *GridView) IndexAt(line, col int) int {
+	index := col*g.Rows() + g.Top + line
+	if line >= g.Height || col >= g.Cols || g.Top+line >= g.Rows() || index >= g.Count {
+		return -1
+	}
+	return index
+}
+
+func (g *GridView) Draw(tty *Terminal, line, col int) {
+	for i := 0; i < g.Height; i += 1 {
+		for j := 0; j < g.Cols; j += 1 {
+			index := g.IndexAt(i, j)
+			if index >= 0 {
+				tty.Highlight(line+i, col+j*g.ColWidth, FitText(g.Item(index), g.ColWidth-1), index == g.Selected)
+			}
+		}
+	}
+}
+
+// ----------- TEXT VIEWER -----------
+
+// read only text scrolled line by line
+type TextViewer struct {
+	Lines  []RichLine
+	Top    int
+	Height int
+	Width  int
+}
+
+func NewTextViewer(text string, height, width int) *TextViewer {
+	viewer := &TextViewer{Height: height, Width: width}
+	viewer.SetText(text)
+	return viewer
+}
+
+// plain text lines
+func (v *TextViewer) SetText(text string) {
+	var lines []RichLine
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		lines = append(lines, RichLine{{line, goncurses.A_NORMAL}})
+	}
+	v.SetLines(lines)
+}
+
+func (v *TextViewer) SetLines(lines []RichLine) {
+	v.Lines = lines
+	v.ScrollTo(v.Top)
+}
+
+// text must be set again when width changes: it is wrapped by caller
+func (v *TextViewer) Resize(height, width int) {
+	v.Height = height
+	v.Width = width
+	v.ScrollTo(v.Top)
+}
+
+// scrolls to show line top first, last page at most
+func (v *TextViewer) ScrollTo(top int) {
+	if top > len(v.Lines)-v.Height {
+		top = len(v.Lines) - v.Height
+	}
+	if top < 0 {
+		top = 0
+	}
+	v.Top = top
+}
+
+func (v *TextViewer) OnKey(key goncurses.Key) bool {
+	switch Keys.Action(key, ListActions...) {
+	case ActionUp:
+		v.ScrollTo(v.Top - 1)
+	case ActionDown:
+		v.ScrollTo(v.Top + 1)
+	case ActionPageUp:
+		v.ScrollTo(v.Top - v.Height)
+	case ActionPageDown:
+		v.ScrollTo(v.Top + v.Height)
+	case ActionHome:
+		v.ScrollTo(0)
+	case ActionEnd:
+		v.ScrollTo(len(v.Lines))
+	default:
+		return false
+	}
+	return true
+}
+
+// scroll position, empty when whole text is visible
+func (v *TextViewer) Position() string {
+	if len(v.Lines) <= v.Height {
+		return ""
+	}
+	last := v.Top + v.Height
+	return fmt.Sprintf("lines %d-%d of %d (%d%%)", v.Top+1, last, len(v.Lines), last*100/len(v.Lines))
+}
+
+func (v *TextViewer) Visible() []string {
+	var lines []string
+	for i := v.Top; i < len(v.Lines) && i < v.Top+v.Height; i += 1 {
+		lines = append(lines, FitText(v.Lines[i].Text(), v.Width))
+	}
+	return lines
+}
+
+// spans are drawn with their attributes, cut at viewer width
+func (v *TextViewer) Draw(tty *Terminal, line, col int) {
+	for i := v.Top; i < len(v.Lines) && i < v.Top+v.Height; i += 1 {
+		x := 0
+		for _, span := range v.Lines[i] {
+			text := CutText(span.Text, v.Width-x)
+			tty.PrintAttr(line+i-v.Top, col+x, text, span.Attr)
+			x += StringWidth(text)
+		}
+		tty.PrintAt(line+i-v.Top, col+x, strings.Repeat(" ", v.Width-x))
+	}
+}
+
+// ----------- CONFIRM DIALOG -----------
+
+// yes/no question; RETURN answers Default
+type ConfirmDialog struct {
+	Message  string
+	Default  bool
+	Answered bool
+	Yes      bool
+}
+
+// returns true when question is answered
+func (d *ConfirmDialog) OnKey(key goncurses.Key) bool {
+	switch Keys.Action(key, ActionYes, ActionNo, ActionOpen) {
+	case ActionYes:
+		d.Yes = true
+	case ActionNo:
+		d.Yes = false
+	case ActionOpen:
+		d.Yes = d.Default
+	default:
+		return false
+	}
+	d.Answered = true
+	return true
+}
+
+func (d *ConfirmDialog) Text() string {
+	if d.Default {
+		return d.Message + " [Y/n]"
+	}
+	return d.Message + " [y/N]"
+}
+
+// asks question on status line, waits for answer
+func (t *Terminal) Confirm(message string, def bool) bool {
+	dialog := &ConfirmDialog{Message: message, Default: def}
+	status := &StatusBar{Message: dialog.Text()}
+	status.Draw(t)
+	for {
+		key := t.GetChar()
+		if key == KeyEOF {
+			return false
+		} else if key == goncurses.KEY_RESIZE {
+			// screen was redrawn
+			status.Draw(t)
+		} else if key == goncurses.KEY_MOUSE {
+			t.Backend.GetMouse()
+		} else if dialog.OnKey(key) {
+			return dialog.Yes
+		}
+	}
+}
+
+// ----------- FILTER -----------
+
+// case insensitive fuzzy match: runes of pattern appear in text in the same order
+func FuzzyMatch(pattern, text string) bool {
+	p := []rune(strings.ToLower(pattern))
+	if len(p) == 0 {
+		return true
+	}
+	i := 0
+	for _, r := range strings.ToLower(text) {
+		if r == p[i] {
+			i += 1
+			if i == len(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// next match after current (step 1) or previous match (step -1), wrapping around; current if no match
+func NextMatch(matches []int, current, step int) int {
+	if len(matches) == 0 {
+		return current
+	}
+	if step > 0 {
+		for _, m := range matches {
+			if m > current {
+				return m
+			}
+		}
+		return matches[0]
+	}
+	for i := len(matches) - 1; i >= 0; i -= 1 {
+		if matches[i] < current {
+			return matches[i]
+		}
+	}
+	return matches[len(matches)-1]
+}
+
+// list filter typed after '/': RETURN ends typing and keeps filter, ESC clears it
+type Filter struct {
+	Input   *TextField
+	Editing bool
+}
+
+func NewFilter() *Filter {
+	return &Filter{Input: NewTextField("/", "", 40)}
+}
+
+func (f *Filter) Pattern() string {
+	return f.Input.Text()
+}
+
+func (f *Filter) Active() bool {
+	return len(f.Input.Value) > 0
+}
+
+func (f *Filter) Match(text string) bool {
+	return FuzzyMatch(f.Pattern(), text)
+}
+
+// returns true if key is handled by filter
+func (f *Filter) OnKey(key goncurses.Key) bool {
+	if !f.Editing {
+		if Keys.Is(key, ActionFilter) {
+			f.Editing = true
+			return true
+		}
+		if Keys.Is(key, ActionCancel) && f.Active() {
+			f.Input.SetText("")
+			return true
+		}
+		return false
+	}
+
+	// pattern is typed: printable keys are not actions
+	if key >= 32 && key <= 255 {
+		return f.Input.OnKey(key)
+	}
+	switch Keys.Action(key, ActionOpen, ActionCancel) {
+	case ActionOpen:
+		f.Editing = false
+	case ActionCancel:
+		f.Editing = false
+		f.Input.SetText("")
+	default:
+		return f.Input.OnKey(key)
+	}
+	return true
+}
+
+// status line: pattern being typed or number of matches, empty without filter
+func (f *Filter) Text(matches int) string {
+	if f.Editing {
+		return "/" + f.Pattern()
+	}
+	if f.Active() {
+		return fmt.Sprintf("/%s: %d matches   %s", f.Pattern(), matches, Keys.Hints(ActionNextMatch, ActionPrevMatch, ActionCancel))
+	}
+	return ""
+}
+
+// ----------- STATUS BAR -----------
+
+// last line of screen: error if set, message if set, help otherwise, and connection state
+type StatusBar struct {
+	Help    string
+	Message string
+	Err     error
+}
+
+func (s *StatusBar) Text() string {
+	if s.Err != nil {
+		return s.Err.Error()
+	}
+	if len(s.Message) > 0 {
+		return s.Message
+	}
+	return s.Help
+}
+
+// clears message and error
+func (s *StatusBar) Clear() {
+	s.Message = ""
+	s.Err = nil
+}
+
+// text on the left, connection state on the right if it fits
+func (s *StatusBar) Line(width int) string {
+	state := ConnectionState()
+	if width < StringWidth(state)+20 {
+		return FitText(s.Text(), width)
+	}
+	return FitText(s.Text(), width-StringWidth(state)-1) + " " + state
+}
+
+func (s *StatusBar) Draw(tty *Terminal) {
+	style := StyleStatus
+	if s.Err != nil {
+		style = StyleError
+	}
+	// last column is not written: window would scroll
+	tty.PrintStyle(tty.Lines-1, 0, s.Line(tty.Cols-1), style)
+}
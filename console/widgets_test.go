@@ -0,0 +1,226 @@
+package console_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/console"
+	"github.com/rthornton128/goncurses"
+)
+
+func TestFitText(t *testing.T) {
+	if got := console.FitText("Lévénez", 10); got != "Lévénez   " {
+		t.Fatalf("Text should be padded (got '%s')\n", got)
+	}
+	if got := console.FitText("Lévénez", 4); got != "Lévé" {
+		t.Fatalf("Text should be cut (got '%s')\n", got)
+	}
+}
+
+func itemText(index int) string {
+	return fmt.Sprintf("item %d", index)
+}
+
+func TestListView(t *testing.T) {
+	list := console.NewListView(10, 3, 8, itemText)
+
+	list.OnKey(goncurses.KEY_DOWN)
+	list.OnKey(goncurses.KEY_DOWN)
+	list.OnKey(goncurses.KEY_DOWN)
+	if list.Selected != 3 || list.Top != 1 {
+		t.Fatalf("List should scroll one line (got selected=%d, top=%d)\n", list.Selected, list.Top)
+	}
+
+	list.OnKey(goncurses.KEY_PAGEDOWN)
+	list.OnKey(goncurses.KEY_PAGEDOWN)
+	if list.Selected != 9 || list.Top != 7 {
+		t.Fatalf("Last item should be selected (got selected=%d, top=%d)\n", list.Selected, list.Top)
+	}
+	visible := list.Visible()
+	if len(visible) != 3 || visible[0] != "item 7  " || visible[2] != "item 9  " {
+		t.Fatalf("Visible items mismatch (got %q)\n", visible)
+	}
+
+	list.OnKey(goncurses.KEY_HOME)
+	if list.Selected != 0 || list.Top != 0 {
+		t.Fatalf("First item should be selected (got selected=%d, top=%d)\n", list.Selected, list.Top)
+	}
+	if list.OnKey('x') {
+		t.Fatal("x is not a list key\n")
+	}
+
+	list.Select(9)
+	list.SetCount(4)
+	if list.Selected != 3 || list.Top != 1 {
+		t.Fatalf("Selection should move to last item (got selected=%d, top=%d)\n", list.Selected, list.Top)
+	}
+
+	list.Resize(2, 6)
+	if list.Top != 2 || list.Visible()[1] != "item 3" {
+		t.Fatalf("Selected item should stay visible after resize (got top=%d, %q)\n", list.Top, list.Visible())
+	}
+
+	empty := console.NewListView(0, 3, 8, itemText)
+	empty.OnKey(goncurses.KEY_DOWN)
+	if empty.Selected != 0 || len(empty.Visible()) != 0 {
+		t.Fatalf("Empty list should stay empty (got selected=%d)\n", empty.Selected)
+	}
+}
+
+func TestGridView(t *testing.T) {
+	// 3 columns of 10: 7 items on 3 rows
+	grid := console.NewGridView(7, 2, 30, 10, itemText)
+	if grid.Cols != 3 || grid.Rows() != 3 {
+		t.Fatalf("3x3 grid expected (got %dx%d)\n", grid.Cols, grid.Rows())
+	}
+
+	grid.OnKey(goncurses.KEY_RIGHT)
+	grid.OnKey(goncurses.KEY_RIGHT)
+	if grid.Selected != 6 {
+		t.Fatalf("Item 6 should be selected (got %d)\n", grid.Selected)
+	}
+	// no item right of 6
+	grid.OnKey(goncurses.KEY_RIGHT)
+	if grid.Selected != 6 || grid.Top != 0 {
+		t.Fatalf("Item 6 should stay selected (got %d)\n", grid.Selected)
+	}
+
+	grid.Select(5)
+	if grid.Top != 1 {
+		t.Fatalf("Grid should scroll to third row (got top=%d)\n", grid.Top)
+	}
+	if grid.IndexAt(1, 1) != 5 || grid.IndexAt(1, 2) != -1 || grid.IndexAt(0, 0) != 1 {
+		t.Fatalf("IndexAt mismatch (got %d, %d, %d)\n", grid.IndexAt(1, 1), grid.IndexAt(1, 2), grid.IndexAt(0, 0))
+	}
+
+	// 2 columns: 7 items on 4 rows, item 5 on second row of second column
+	grid.Resize(2, 25)
+	if grid.Cols != 2 || grid.Rows() != 4 || grid.IndexAt(1, 1) != 5 {
+		t.Fatalf("2x4 grid expected (got %dx%d, top=%d)\n", grid.Cols, grid.Rows(), grid.Top)
+	}
+}
+
+func TestTextViewer(t *testing.T) {
+	viewer := console.NewTextViewer("one\ntwo\nthree\nfour\nfive\n", 2, 10)
+	if len(viewer.Lines) != 5 {
+		t.Fatalf("5 lines expected (got %d)\n", len(viewer.Lines))
+	}
+
+	if viewer.Position() != "lines 1-2 of 5 (40%)" {
+		t.Fatalf("Position mismatch (got '%s')\n", viewer.Position())
+	}
+
+	viewer.OnKey(goncurses.KEY_END)
+	if viewer.Top != 3 || viewer.Position() != "lines 4-5 of 5 (100%)" {
+		t.Fatalf("Last page should be shown (got top=%d, '%s')\n", viewer.Top, viewer.Position())
+	}
+	viewer.OnKey(goncurses.KEY_PAGEUP)
+	viewer.OnKey(goncurses.KEY_UP)
+	if visible := viewer.Visible(); viewer.Top != 0 || visible[0] != "one       " {
+		t.Fatalf("First page should be shown (got top=%d, %q)\n", viewer.Top, visible)
+	}
+
+	viewer.Resize(8, 10)
+	if viewer.Position() != "" {
+		t.Fatalf("No position when text fits (got '%s')\n", viewer.Position())
+	}
+}
+
+func TestConfirmDialog(t *testing.T) {
+	dialog := &console.ConfirmDialog{Message: "Download book?", Default: true}
+	if dialog.Text() != "Download book? [Y/n]" {
+		t.Fatalf("Text mismatch (got '%s')\n", dialog.Text())
+	}
+	if dialog.OnKey('x') || dialog.Answered {
+		t.Fatal("x should not answer dialog\n")
+	}
+	if !dialog.OnKey(goncurses.KEY_RETURN) || !dialog.Yes {
+		t.Fatal("RETURN should answer default\n")
+	}
+
+	dialog = &console.ConfirmDialog{Message: "Delete?"}
+	if !dialog.OnKey('n') || dialog.Yes || !dialog.Answered {
+		t.Fatal("n should answer no\n")
+	}
+}
+
+func TestStatusBar(t *testing.T) {
+	status := &console.StatusBar{Help: "q: quit"}
+	if status.Text() != "q: quit" {
+		t.Fatalf("Help should be shown (got '%s')\n", status.Text())
+	}
+	status.Message = "Book saved"
+	if status.Text() != "Book saved" {
+		t.Fatalf("Message should be shown (got '%s')\n", status.Text())
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	for _, text := range []string{"Go", "Learning Go", "GOLANG", "garbage collector"} {
+		if !console.FuzzyMatch("go", text) {
+			t.Fatalf("'go' should match '%s'\n", text)
+		}
+	}
+	for _, text := range []string{"Programming", "Databases", "o g"} {
+		if console.FuzzyMatch("go", text) {
+			t.Fatalf("'go' should not match '%s'\n", text)
+		}
+	}
+	if !console.FuzzyMatch("", "anything") {
+		t.Fatal("Empty pattern should match\n")
+	}
+}
+
+func TestNextMatch(t *testing.T) {
+	matches := []int{2, 5, 9}
+	if next := console.NextMatch(matches, 5, 1); next != 9 {
+		t.Fatalf("Next match should be 9 (got %d)\n", next)
+	}
+	if next := console.NextMatch(matches, 9, 1); next != 2 {
+		t.Fatalf("Next match should wrap to 2 (got %d)\n", next)
+	}
+	if prev := console.NextMatch(matches, 2, -1); prev != 9 {
+		t.Fatalf("Previous match should wrap to 9 (got %d)\n", prev)
+	}
+	if prev := console.NextMatch(matches, 4, -1); prev != 2 {
+		t.Fatalf("Previous match should be 2 (got %d)\n", prev)
+	}
+	if next := console.NextMatch(nil, 4, 1); next != 4 {
+		t.Fatalf("Current index expected without match (got %d)\n", next)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	filter := console.NewFilter()
+	if filter.OnKey('g') || filter.Active() {
+		t.Fatal("Keys should not be typed before '/'\n")
+	}
+
+	for _, key := range []goncurses.Key{'/', 'g', 'o'} {
+		if !filter.OnKey(key) {
+			t.Fatalf("Key %d should be handled by filter\n", key)
+		}
+	}
+	if filter.Pattern() != "go" || filter.Text(2) != "/go" {
+		t.Fatalf("Pattern mismatch (got '%s', '%s')\n", filter.Pattern(), filter.Text(2))
+	}
+	if filter.OnKey(goncurses.KEY_DOWN) {
+		t.Fatal("DOWN should move in list while typing\n")
+	}
+
+	filter.OnKey(goncurses.KEY_RETURN)
+	if filter.Editing || !filter.Active() || !strings.HasPrefix(filter.Text(2), "/go: 2 matches") {
+		t.Fatalf("Filter should stay active after RETURN (got '%s')\n", filter.Text(2))
+	}
+	if filter.OnKey('n') {
+		t.Fatal("n should not be handled by filter after RETURN\n")
+	}
+
+	if !filter.OnKey(goncurses.KEY_ESC) || filter.Active() || filter.Text(0) != "" {
+		t.Fatal("ESC should clear filter\n")
+	}
+	if filter.OnKey(goncurses.KEY_ESC) {
+		t.Fatal("ESC should not be handled without filter\n")
+	}
+}
@@ -1,16 +1,26 @@
 package download
 
 import (
+	"crypto"
+	crand "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -30,21 +40,65 @@ type StorageClient interface {
 
 }
 
+// optional interface: clients decoding vendor specific error responses of download request
+type DownloadErrorHandler interface {
+	DownloadError(resp utils.IHttpResponse) error
+}
+
+// optional interface: clients reading file metadata from vendor
+type FileInfoReader interface {
+	ReadFileInfo(fileId string) (*FileInfo, error)
+}
+
+// optional interface: clients whose download url depends on metadata read by ReadFileInfo
+type FileUrlBuilder interface {
+	FileUrl(info *FileInfo) string
+}
+
+// optional interface: clients adding vendor specific headers to download request
+type DownloadHeadersProvider interface {
+	DownloadHeaders(fileId string) map[string]string
+}
+
+// optional interface: clients able to get a new token without user interaction
+type TokenRenewer interface {
+	RenewToken() error
+}
+
+type FileInfo struct {
+	Id       string
+	Name     string
+	Size     int64
+	Hash     string
+	MimeType string
+}
+
 type StorageData struct {
 	VendorId      int
 	VendorCode    string
+	Account       string
+	Store         config.FileStore
 	Token         string
 	RefreshToken  string
 	TokenValidity int64
 	Client        StorageClient
 }
 
-var _StorageData map[string]*StorageData
+// storage data by FILE_STORE id: one entry (and token cache) per vendor account
+var _StorageData map[int]*StorageData
 
-type GoogleClient struct{}
-type OneDriveClient struct{}
-type BoxComClient struct{}
-type PCloudClient struct{}
+type GoogleClient struct {
+	Data *StorageData
+}
+type OneDriveClient struct {
+	Data *StorageData
+}
+type BoxComClient struct {
+	Data *StorageData
+}
+type PCloudClient struct {
+	Data *StorageData
+}
 
 type myHandler struct {
 	Ch      chan<- string
@@ -83,10 +137,10 @@ func Auth(client StorageClient) error {
 
 func DownloadFile(book *utils.BookDownload) error {
 
-	data, ok := _StorageData[book.VendorCode]
+	data, ok := _StorageData[book.StorageId]
 
 	if !ok {
-		return fmt.Errorf("Vendor %s not found", book.VendorCode)
+		return fmt.Errorf("Vendor %s (store %d) not found", book.VendorCode, book.StorageId)
 	}
 
 	if data.Client == nil {
@@ -94,76 +148,165 @@ func DownloadFile(book *utils.BookDownload) error {
 	}
 
 	if len(data.Token) == 0 || data.TokenValidity <= time.Now().Unix() {
-		err := Auth(data.Client)
+		renewed := false
+		if renewer, ok := data.Client.(TokenRenewer); ok {
+			err := renewer.RenewToken()
+			if err == nil {
+				renewed = true
+			} else {
+				log.Printf("Token renewal failed: %v\n", err)
+			}
+		}
+		if !renewed {
+			err := Auth(data.Client)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// metadata is read once, for file name and size and for url builder
+	builder, has_builder := data.Client.(FileUrlBuilder)
+	var info *FileInfo
+	if reader, ok := data.Client.(FileInfoReader); ok && (len(book.FileName) == 0 || book.FileSize == 0 || has_builder) {
+		var err error
+		info, err = reader.ReadFileInfo(book.FileId)
 		if err != nil {
 			return err
 		}
+		if len(book.FileName) == 0 {
+			book.FileName = info.Name
+		}
+		if book.FileSize == 0 {
+			book.FileSize = int(info.Size)
+		}
 	}
 
-	fileurl := data.Client.DownloadUrl(book.FileId)
+	var fileurl string
+	if has_builder && info != nil {
+		fileurl = builder.FileUrl(info)
+	} else {
+		fileurl = data.Client.DownloadUrl(book.FileId)
+	}
 
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", fileurl, nil)
+	req := utils.CreateRequest(fileurl)
 
-	if err != nil {
-		return err
+	req.AddHeaders(map[string]string{"Authorization": "Bearer " + data.Token})
+	if provider, ok := data.Client.(DownloadHeadersProvider); ok {
+		req.AddHeaders(provider.DownloadHeaders(book.FileId))
 	}
 
-	req.Header.Add("Authorization", "Bearer "+data.Token)
-
-	resp, err := client.Do(req)
+	resp := req.GetStream()
 
-	if err != nil {
-		log.Printf("%v\n", err)
-		return err
+	if resp == nil {
+		log.Printf("%v\n", req.Error())
+		return req.Error()
 	}
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+	body := resp.Body()
+	defer body.Close()
 
-		file_path := path.Join(config.GetConfig().Dirs.Download, book.FileName)
-		out, _ := os.Create(file_path)
-		_, err = io.Copy(out, resp.Body)
+	if resp.StatusCode() >= 200 && resp.StatusCode() < 400 {
 
-		defer resp.Body.Close()
+		file_path := path.Join(config.GetConfig().Dirs.Download, book.FileName)
+		out, err := os.Create(file_path)
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, hash), body)
+		out.Close()
 
 		if err != nil {
 			return err
 		}
 
-		out.Close()
+		// file hash helps finding duplicate books
+		err = utils.SetFileHash(book.BookId, book.StorageId, hex.EncodeToString(hash.Sum(nil)))
+		if err != nil {
+			log.Printf("Cannot save hash of %s: %v\n", file_path, err)
+		}
 
 		log.Println("File downloaded", file_path)
 	} else {
-		return fmt.Errorf("Error file download Code=%d, status=%s", resp.StatusCode, resp.Status)
+		if handler, ok := data.Client.(DownloadErrorHandler); ok {
+			return handler.DownloadError(resp)
+		}
+		return fmt.Errorf("Error file download Code=%d, status=%s", resp.StatusCode(), http.StatusText(resp.StatusCode()))
 	}
 	return nil
 }
 
+// GET request decoding JSON content in v; content is not decoded if status >= 400
+func GetJson(url_str string, headers map[string]string, v interface{}) (utils.IHttpResponse, error) {
+	req := utils.CreateRequest(url_str)
+	req.AddHeaders(headers)
+
+	resp := req.Get()
+	if resp == nil {
+		return nil, req.Error()
+	}
+	if resp.StatusCode() >= 400 {
+		return resp, nil
+	}
+	return resp, json.Unmarshal(resp.Content(), v)
+}
+
 func InitVendorsData() {
 	vendors, _ := utils.GetVendors()
-	_StorageData = make(map[string]*StorageData)
+	_StorageData = make(map[int]*StorageData)
+	storage := config.GetConfig().Storage
 
 	for _, v := range vendors {
 		data := &StorageData{}
 		data.VendorId = v.Id
 		data.VendorCode = v.VendorCode
+		data.Account = v.Account
 		data.TokenValidity = 0
 
 		if v.VendorCode == "GOOG" {
-			data.Client = &GoogleClient{}
+			data.Store = storage.Google.Account(v.Account)
+			data.Client = &GoogleClient{data}
 		}
 		if v.VendorCode == "MSOD" {
-			data.Client = &OneDriveClient{}
+			data.Store = storage.OneDrive.Account(v.Account)
+			data.Client = &OneDriveClient{data}
 		}
 		if v.VendorCode == "BOX" {
-			data.Client = &BoxComClient{}
+			data.Store = storage.Box.Account(v.Account)
+			data.Client = &BoxComClient{data}
 		}
-		_StorageData[v.VendorCode] = data
+		_StorageData[v.Id] = data
 	}
 }
 
-func OnToken(token, code string) error {
-	data, _ := _StorageData[code]
+// login state of a file store account: token obtained and not expired
+type VendorState struct {
+	VendorId   int
+	VendorCode string
+	Account    string
+	LoggedIn   bool
+}
+
+// data of file store loaded by InitVendorsData, nil if not found
+func GetStorageData(storeId int) *StorageData {
+	return _StorageData[storeId]
+}
+
+// states of file stores loaded by InitVendorsData, by store id
+func GetVendorsState() []VendorState {
+	var states []VendorState
+	now := time.Now().Unix()
+	for _, data := range _StorageData {
+		states = append(states, VendorState{data.VendorId, data.VendorCode, data.Account, len(data.Token) > 0 && data.TokenValidity > now})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].VendorId < states[j].VendorId
+	})
+	return states
+}
+
+func OnToken(token string, data *StorageData) error {
 
 	splitted := strings.Split(token, "&")
 	const access_token = "access_token"
@@ -184,9 +327,8 @@ func OnToken(token, code string) error {
 				tsec, err := strconv.Atoi(tokens[1])
 				if err != nil {
 					tsec = 3600
-				} else {
-					data.TokenValidity = time.Now().Add(time.Duration(tsec)).Unix()
 				}
+				data.TokenValidity = time.Now().Add(time.Duration(tsec) * time.Second).Unix()
 			}
 		}
 		if len(s) > len(error_token) && s[:len(error_token)] == error_token {
@@ -223,6 +365,22 @@ func SplitTokens(tokenstr string) map[string]string {
 	return tokens
 }
 
+// returns configured value or default value if not set
+func StoreValue(value, default_value string) string {
+	if len(value) == 0 {
+		return default_value
+	}
+	return value
+}
+
+// returns space separated scopes of store or default scope if none configured
+func StoreScopes(store config.FileStore, default_scope string) string {
+	if len(store.Scopes) == 0 {
+		return default_scope
+	}
+	return strings.Join(store.Scopes, " ")
+}
+
 //
 /// HTTP SERVER for API REDIRECTION
 //
@@ -301,80 +459,268 @@ func StartServer(ch chan string, url string) {
 // ----------- Google Drive  -----------
 
 func (goog *GoogleClient) RedirectUri() string {
-	return config.GetConfig().Storage.Google.RedirectUri
+	return goog.Data.Store.RedirectUri
 }
 
 func (goog *GoogleClient) AuthUrl() string {
-	const BaseUrl = "https://accounts.google.com/o/oauth2/v2/auth"
-	ClientId := config.GetConfig().Storage.Google.ClientId
+	store := goog.Data.Store
+
+	BaseUrl := StoreValue(store.AuthUrl, "https://accounts.google.com/o/oauth2/v2/auth")
 
 	redirect_uri := goog.RedirectUri()
 
-	args := map[string]string{"client_id": ClientId, "redirect_uri": redirect_uri, "response_type": "token",
-		"scope": "https://www.googleapis.com/auth/drive.readonly", "login_hint": "tcn75323@gmail.com"}
+	args := map[string]string{"client_id": store.ClientId, "redirect_uri": redirect_uri, "response_type": "token",
+		"scope": url.QueryEscape(StoreScopes(store, "https://www.googleapis.com/auth/drive.readonly"))}
+
+	if len(store.LoginHint) > 0 {
+		args["login_hint"] = url.QueryEscape(store.LoginHint)
+	}
 
 	return utils.AddUrlArguments(BaseUrl, args)
 }
 
+type GoogleFile struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     string `json:"size"`
+}
+
+// google documents cannot be downloaded, they are exported to one of these formats;
+// Export setting of store changes format by document type
+var GoogleExportDefaults = map[string]string{
+	"application/vnd.google-apps.document":     "application/epub+zip",
+	"application/vnd.google-apps.spreadsheet":  "application/pdf",
+	"application/vnd.google-apps.presentation": "application/pdf",
+	"application/vnd.google-apps.drawing":      "application/pdf",
+}
+
+// extensions added to names of exported documents
+var GoogleExportExtensions = map[string]string{
+	"application/pdf":      ".pdf",
+	"application/epub+zip": ".epub",
+}
+
+const GoogleFilesUrl = "https://www.googleapis.com/drive/v3/files/"
+
+func IsGoogleDocument(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "application/vnd.google-apps.")
+}
+
+// url of file content; google documents are exported
+func (goog *GoogleClient) FileUrl(info *FileInfo) string {
+	if IsGoogleDocument(info.MimeType) {
+		return goog.ExportUrl(info.Id, info.MimeType)
+	}
+	return goog.DownloadUrl(info.Id)
+}
+
+// url of binary file content, see FileUrl for google documents
 func (goog *GoogleClient) DownloadUrl(fileId string) string {
 
-	const URL = "https://www.googleapis.com/drive/v3/files/"
-	const SCOPE = "https://www.googleapis.com/auth/drive.readonly"
+	args := map[string]string{"alt": "media", "supportsAllDrives": "true"}
+	if goog.Data.Store.AcknowledgeAbuse {
+		args["acknowledgeAbuse"] = "true"
+	}
+	return utils.AddUrlArguments(GoogleFilesUrl+fileId, args)
+}
 
-	return URL + fileId + "?alt=media"
+// format of exported google document: Export setting of store, GoogleExportDefaults, or pdf
+func (goog *GoogleClient) ExportType(mimeType string) string {
+	export_type, ok := goog.Data.Store.Export[mimeType]
+	if !ok {
+		export_type, ok = GoogleExportDefaults[mimeType]
+	}
+	if !ok {
+		export_type = "application/pdf"
+	}
+	return export_type
 }
 
-func (goog *GoogleClient) RefreshToken() string {
-	const code = "GOOG"
-	data, _ := _StorageData[code]
+func (goog *GoogleClient) ExportUrl(fileId, mimeType string) string {
+	return utils.AddUrlArguments(GoogleFilesUrl+fileId+"/export", map[string]string{"mimeType": url.QueryEscape(goog.ExportType(mimeType))})
+}
+
+// metadata of file; supportsAllDrives is required for files of shared drives
+func (goog *GoogleClient) FileInfo(fileId string) (*GoogleFile, error) {
 
-	return data.RefreshToken
+	args := map[string]string{"fields": "id,name,mimeType,size", "supportsAllDrives": "true"}
+	file := &GoogleFile{}
+
+	resp, err := GetJson(utils.AddUrlArguments(GoogleFilesUrl+fileId, args), map[string]string{"Authorization": "Bearer " + goog.Data.Token}, file)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, goog.DownloadError(resp)
+	}
+	return file, nil
+}
 
+func (goog *GoogleClient) ReadFileInfo(fileId string) (*FileInfo, error) {
+	file, err := goog.FileInfo(fileId)
+	if err != nil {
+		return nil, err
+	}
+	// size is not set for google documents, name has no extension
+	size, _ := strconv.ParseInt(file.Size, 10, 64)
+	name := file.Name
+	if IsGoogleDocument(file.MimeType) {
+		ext := GoogleExportExtensions[goog.ExportType(file.MimeType)]
+		if !strings.HasSuffix(strings.ToLower(name), ext) {
+			name += ext
+		}
+	}
+	return &FileInfo{file.Id, name, size, "", file.MimeType}, nil
+}
+
+func (goog *GoogleClient) DownloadError(resp utils.IHttpResponse) error {
+
+	//{"error":{"code":403,"message":"This file has been identified as malware or spam and cannot be downloaded.",
+	// "errors":[{"domain":"global","reason":"cannotDownloadAbusiveFile","message":"..."}]}}
+	type Response_ struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Errors  []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	body := &Response_{}
+	err := json.Unmarshal(resp.Content(), body)
+	if err != nil || len(body.Error.Message) == 0 {
+		return fmt.Errorf("Error file download Code=%d, status=%s", resp.StatusCode(), http.StatusText(resp.StatusCode()))
+	}
+
+	for _, e := range body.Error.Errors {
+		if e.Reason == "cannotDownloadAbusiveFile" {
+			return fmt.Errorf("%s Set acknowledge-abuse=true in Google storage configuration to download it anyway.", body.Error.Message)
+		}
+	}
+	return fmt.Errorf("Error file download Code=%d: %s", body.Error.Code, body.Error.Message)
+}
+
+func (goog *GoogleClient) RefreshToken() string {
+	return goog.Data.RefreshToken
 }
 
 func (goog *GoogleClient) OnToken(token string) error {
-	const code = "GOOG"
 
 	//access_token=ya29.a0ARrdaM-sVhN8knzKB9QXXOgn_Z_TIdbffDWnTapzSH0_zDI7SL-CQjza_tg15MhzScp8HUFcOVF-YbSRm5BiOThl57RsmihjpZcJHj7ERpXdSNXXKy-9-uwRxBpyA0rCDg7-7kDXu4NvouG0W2tob9xZzLoW
 	//&token_type=Bearer&expires_in=3599&scope=https://www.googleapis.com/auth/drive.readonly
-	return OnToken(token, code)
+	return OnToken(token, goog.Data)
 }
 
 // -----------  OneDrive -----------
 
 func (od *OneDriveClient) AuthUrl() string {
 	//"https://login.microsoftonline.com/common/oauth2/v2.0/authorize?client_id={client_id}&scope={scope}&response_type=token&redirect_uri={redirect_uri}"
-	const BASE_URL = "https://login.microsoftonline.com/common/oauth2/v2.0/authorize?client_id=%s&scope=%s&response_type=token&redirect_uri=%s"
+	const BASE_URL = "https://login.microsoftonline.com/%s/oauth2/v2.0/authorize"
 
-	CLIENT_ID := config.GetConfig().Storage.OneDrive.ClientId
-	const SCOPE = "Files.Read"
+	store := od.Data.Store
+
+	// tenant is "common", "organizations", "consumers" or a tenant id/domain
+	tenant := StoreValue(store.Tenant, "common")
+	auth_url := StoreValue(store.AuthUrl, fmt.Sprintf(BASE_URL, tenant))
 	//"https://myapp.com/auth-redirect#access_token=EwC...EB&authentication_token=eyJ...3EM&token_type=bearer&expires_in=3600&scope=onedrive.readwrite&user_id=3626...1d"
-	url := fmt.Sprintf(BASE_URL, CLIENT_ID, SCOPE, url.QueryEscape(od.RedirectUri()))
-	return url
+	args := map[string]string{"client_id": store.ClientId, "response_type": "token",
+		"scope":        url.QueryEscape(StoreScopes(store, "Files.Read")),
+		"redirect_uri": url.QueryEscape(od.RedirectUri())}
+
+	if len(store.LoginHint) > 0 {
+		args["login_hint"] = url.QueryEscape(store.LoginHint)
+	}
+
+	return utils.AddUrlArguments(auth_url, args)
 }
 
-func (od *OneDriveClient) DownloadUrl(fileId string) string {
+const GraphUrl = "https://graph.microsoft.com/v1.0"
+
+type OneDriveItem struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	File struct {
+		MimeType string `json:"mimeType"`
+		Hashes   struct {
+			Sha1Hash     string `json:"sha1Hash"`
+			QuickXorHash string `json:"quickXorHash"`
+		} `json:"hashes"`
+	} `json:"file"`
+}
 
-	const URL = "https://graph.microsoft.com/v1.0/me/drive/items/%s/content"
-	const SCOPE = ""
+// graph path of drive item addressed by file id. Accepted file ids:
+//
+//	https://1drv.ms/... (sharing url copied from OneDrive UI)
+//	/drives/{drive-id}/items/{item-id}, /shares/{share-id}/driveItem, /me/drive/items/{item-id}
+//	{drive-id}!{item-id} (OneDrive personal item id)
+//	{item-id}
+func (od *OneDriveClient) ItemPath(fileId string) string {
+
+	fileId = strings.TrimSpace(fileId)
+
+	if strings.HasPrefix(fileId, "https://") || strings.HasPrefix(fileId, "http://") {
+		// https://docs.microsoft.com/en-us/graph/api/shares-get#encoding-sharing-urls
+		share_id := "u!" + base64.RawURLEncoding.EncodeToString([]byte(fileId))
+		return "/shares/" + share_id + "/driveItem"
+	}
+	if strings.HasPrefix(fileId, "/drives/") || strings.HasPrefix(fileId, "/shares/") || strings.HasPrefix(fileId, "/me/") {
+		return strings.TrimSuffix(fileId, "/")
+	}
+	if strings.Contains(fileId, "!") {
+		drive_id := strings.SplitN(fileId, "!", 2)[0]
+		return "/drives/" + drive_id + "/items/" + fileId
+	}
 
+	// legacy file ids stored as <x>.<y>.<item-id>
 	splitted := strings.Split(fileId, ".")
 	if len(splitted) == 3 {
 		fileId = splitted[2]
 	}
-	return fmt.Sprintf(URL, fileId)
+	return "/me/drive/items/" + fileId
+}
+
+func (od *OneDriveClient) DownloadUrl(fileId string) string {
+	return GraphUrl + od.ItemPath(fileId) + "/content"
+}
+
+func (od *OneDriveClient) FileInfo(fileId string) (*OneDriveItem, error) {
+
+	item_url := GraphUrl + od.ItemPath(fileId) + "?$select=id,name,size,file"
+	item := &OneDriveItem{}
+
+	resp, err := GetJson(item_url, map[string]string{"Authorization": "Bearer " + od.Data.Token}, item)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("Drive item %s request returned status=%d status code=%s", fileId, resp.StatusCode(), http.StatusText(resp.StatusCode()))
+	}
+	return item, nil
+}
+
+func (od *OneDriveClient) ReadFileInfo(fileId string) (*FileInfo, error) {
+	item, err := od.FileInfo(fileId)
+	if err != nil {
+		return nil, err
+	}
+	hash := item.File.Hashes.Sha1Hash
+	if len(hash) == 0 {
+		hash = item.File.Hashes.QuickXorHash
+	}
+	return &FileInfo{item.Id, item.Name, item.Size, hash, ""}, nil
 }
 
 func (od *OneDriveClient) RedirectUri() string {
-	return config.GetConfig().Storage.OneDrive.RedirectUri
+	return od.Data.Store.RedirectUri
 }
 
 func (msod *OneDriveClient) OnToken(token string) error {
-	const code = "MSOD"
 
 	//access_token=ya29.a0ARrdaM-sVhN8knzKB9QXXOgn_Z_TIdbffDWnTapzSH0_zDI7SL-CQjza_tg15MhzScp8HUFcOVF-YbSRm5BiOThl57RsmihjpZcJHj7ERpXdSNXXKy-9-uwRxBpyA0rCDg7-7kDXu4NvouG0W2tob9xZzLoW
 	//&token_type=Bearer&expires_in=3599&scope=File.Read
-	err := OnToken(token, code)
+	err := OnToken(token, msod.Data)
 	if err != nil {
 		return err
 	}
@@ -388,66 +734,252 @@ func (msod *OneDriveClient) RefreshToken() string {
 // ----------- BOX.COM -----------
 
 func (bx *BoxComClient) AuthUrl() string {
-	CLIENT_ID := config.GetConfig().Storage.Box.ClientId
+	store := bx.Data.Store
 	//curl -i -X GET "https://account.box.com/api/oauth2/authorize?response_type=code&client_id=ly1nj6n11vionaie65emwzk575hnnmrk&redirect_uri=http://example.com/auth/callback"
-	const BASE_URL = "https://account.box.com/api/oauth2/authorize/?response_type=code&client_id=%s&redirect_uri=%s"
+	BaseUrl := StoreValue(store.AuthUrl, "https://account.box.com/api/oauth2/authorize/")
+
+	args := map[string]string{"response_type": "code", "client_id": store.ClientId, "redirect_uri": bx.RedirectUri()}
+
+	// box uses the scopes configured on the application when none is requested
+	if len(store.Scopes) > 0 {
+		args["scope"] = url.QueryEscape(StoreScopes(store, ""))
+	}
+
+	return utils.AddUrlArguments(BaseUrl, args)
+}
+
+const BoxApiUrl = "https://api.box.com/2.0"
+
+type BoxItem struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Sha1 string `json:"sha1"`
+}
 
-	return fmt.Sprintf(BASE_URL, CLIENT_ID, bx.RedirectUri())
+// shared links (https://app.box.com/s/...) can be stored as file id
+func IsBoxSharedLink(fileId string) bool {
+	return strings.HasPrefix(fileId, "https://") || strings.HasPrefix(fileId, "http://")
 }
 
+const BoxContentUrl = BoxApiUrl + "/files/%s/content/"
+
+// url of file content by file id; file id of shared links is read by ReadFileInfo, see FileUrl
 func (bx *BoxComClient) DownloadUrl(fileId string) string {
-	const URL = "https://api.box.com/2.0/files/%s/content/"
-	return fmt.Sprintf(URL, fileId)
+	return fmt.Sprintf(BoxContentUrl, fileId)
+}
+
+// url of file content; info of shared link has id of shared file
+func (bx *BoxComClient) FileUrl(info *FileInfo) string {
+	return fmt.Sprintf(BoxContentUrl, info.Id)
+}
+
+// files of shared links are only accessible with shared link in BoxApi header
+func (bx *BoxComClient) DownloadHeaders(fileId string) map[string]string {
+	if IsBoxSharedLink(fileId) {
+		return map[string]string{"BoxApi": "shared_link=" + fileId}
+	}
+	return nil
+}
+
+func (bx *BoxComClient) SharedItem(link string) (*BoxItem, error) {
+	return bx.getItem(BoxApiUrl+"/shared_items?fields=id,type,name,size,sha1", link)
+}
+
+func (bx *BoxComClient) ReadFileInfo(fileId string) (*FileInfo, error) {
+	var item *BoxItem
+	var err error
+
+	if IsBoxSharedLink(fileId) {
+		item, err = bx.SharedItem(fileId)
+	} else {
+		item, err = bx.getItem(BoxApiUrl+"/files/"+fileId+"?fields=id,type,name,size,sha1", "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FileInfo{item.Id, item.Name, item.Size, item.Sha1, ""}, nil
+}
+
+func (bx *BoxComClient) getItem(item_url, shared_link string) (*BoxItem, error) {
+
+	headers := map[string]string{"Authorization": "Bearer " + bx.Data.Token}
+	if len(shared_link) > 0 {
+		headers["BoxApi"] = "shared_link=" + shared_link
+	}
+
+	item := &BoxItem{}
+	resp, err := GetJson(item_url, headers, item)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("Box item request returned status=%d status code=%s", resp.StatusCode(), http.StatusText(resp.StatusCode()))
+	}
+	if item.Type != "file" {
+		return nil, fmt.Errorf("Box item %s is a %s, not a file", item.Id, item.Type)
+	}
+	return item, nil
 }
 
 func (bx *BoxComClient) RedirectUri() string {
-	return config.GetConfig().Storage.Box.RedirectUri
+	return bx.Data.Store.RedirectUri
+}
+
+func (bx *BoxComClient) TokenUrl() string {
+	return StoreValue(bx.Data.Store.TokenUrl, "https://api.box.com/oauth2/token/")
 }
 
 func (bx *BoxComClient) OnToken(token string) error {
 	//const code = "BOX"
-	const URL = "https://api.box.com/oauth2/token/"
-	CLIENT_ID := config.GetConfig().Storage.Box.ClientId
-	CLIENT_SECRET := config.GetConfig().Storage.Box.Secret
-
 	kval := SplitTokens(token)
 	code, ok := kval["code"]
 	if len(code) == 0 {
 		return errors.New("Code has 0 length")
 	}
 	if ok {
-		resp, err := http.PostForm(URL, url.Values{"client_id": {CLIENT_ID}, "client_secret": {CLIENT_SECRET}, "code": {code}, "grant_type": {"authorization_code"}})
+		return bx.RequestToken(url.Values{"code": {code}, "grant_type": {"authorization_code"}})
+	}
+	return errors.New("Token \"code\" expected in url paramters")
+}
 
-		if err != nil {
-			return err
-		}
+// get token without browser: refresh token if any, else server authentication of enterprise app
+func (bx *BoxComClient) RenewToken() error {
+	store := bx.Data.Store
 
-		if resp.StatusCode >= 400 {
-			return fmt.Errorf("POST request for token returned status=%d status code=%s", resp.StatusCode, resp.Status)
+	if len(bx.Data.RefreshToken) > 0 {
+		err := bx.RequestToken(url.Values{"refresh_token": {bx.Data.RefreshToken}, "grant_type": {"refresh_token"}})
+		if err == nil {
+			return nil
 		}
+		// refresh token are single use and expire after 60 days
+		bx.Data.RefreshToken = ""
+		log.Printf("Box refresh token rejected: %v\n", err)
+	}
 
-		type Response_ struct {
-			AccesToken   string `json:"access_token"`
-			RefreshToken string `json:"refresh_token"`
-			ExpiresIn    int64  `json:"expires_in"`
-		}
-		body := &Response_{}
-		err = json.NewDecoder(resp.Body).Decode(body)
+	switch store.AuthMode {
+	case "ccg":
+		return bx.RequestToken(url.Values{"grant_type": {"client_credentials"},
+			"box_subject_type": {StoreValue(store.SubjectType, "enterprise")}, "box_subject_id": {store.SubjectId}})
+	case "jwt":
+		assertion, err := bx.JwtAssertion()
 		if err != nil {
 			return err
 		}
-		_StorageData["BOX"].Token = body.AccesToken
-		_StorageData["BOX"].RefreshToken = body.RefreshToken
-		_StorageData["BOX"].TokenValidity = time.Now().Add(time.Duration(body.ExpiresIn)).Unix()
+		return bx.RequestToken(url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}})
+	}
+	return errors.New("No Box refresh token or server authentication configured")
+}
 
-	} else {
-		return errors.New("Token \"code\" expected in url paramters")
+// POST token request; client id and secret are added to values
+func (bx *BoxComClient) RequestToken(values url.Values) error {
+
+	values.Set("client_id", bx.Data.Store.ClientId)
+	values.Set("client_secret", bx.Data.Store.Secret)
+
+	req := utils.CreateRequest(bx.TokenUrl())
+	resp := req.Post("application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+
+	if resp == nil {
+		return req.Error()
+	}
+
+	if resp.StatusCode() >= 400 {
+		return fmt.Errorf("POST request for token returned status=%d status code=%s", resp.StatusCode(), http.StatusText(resp.StatusCode()))
 	}
+
+	type Response_ struct {
+		AccesToken   string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	body := &Response_{}
+	err := json.Unmarshal(resp.Content(), body)
+	if err != nil {
+		return err
+	}
+	bx.Data.Token = body.AccesToken
+	bx.Data.RefreshToken = body.RefreshToken
+	bx.Data.TokenValidity = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second).Unix()
+
 	return nil
 }
 
+// JWT assertion signed with app private key (PEM file, PKCS1 or PKCS8, not encrypted)
+func (bx *BoxComClient) JwtAssertion() (string, error) {
+	store := bx.Data.Store
+
+	if len(store.PrivateKey) == 0 {
+		return "", errors.New("Box JWT authentication: private-key not configured")
+	}
+	pem_data, err := ioutil.ReadFile(config.ExpandPath(store.PrivateKey))
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(pem_data)
+	if block == nil {
+		return "", fmt.Errorf("No PEM data found in %s", store.PrivateKey)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return "", fmt.Errorf("Encrypted private key %s not supported: decrypt it with openssl pkcs8", store.PrivateKey)
+	}
+
+	var key *rsa.PrivateKey
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		var parsed interface{}
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			key, ok = parsed.(*rsa.PrivateKey)
+			if !ok {
+				err = errors.New("Private key is not a RSA key")
+			}
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	jti := make([]byte, 32)
+	_, err = crand.Read(jti)
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT", "kid": store.KeyId}
+	claims := map[string]interface{}{
+		"iss":          store.ClientId,
+		"sub":          store.SubjectId,
+		"box_sub_type": StoreValue(store.SubjectType, "enterprise"),
+		"aud":          strings.TrimSuffix(bx.TokenUrl(), "/"),
+		"jti":          hex.EncodeToString(jti),
+		"exp":          time.Now().Add(45 * time.Second).Unix(),
+	}
+
+	header_json, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claims_json, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signing_input := base64.RawURLEncoding.EncodeToString(header_json) + "." + base64.RawURLEncoding.EncodeToString(claims_json)
+	digest := sha256.Sum256([]byte(signing_input))
+
+	signature, err := rsa.SignPKCS1v15(crand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signing_input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
 func (bx *BoxComClient) RefreshToken() string {
-	return _StorageData["BOX"].RefreshToken
+	return bx.Data.RefreshToken
 }
 
 // ----------- PCLOUD -----------
@@ -0,0 +1,285 @@
+package download_test
+
+import (
+	"crypto"
+	crand "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bookstore-go/config"
+	"github.com/bookstore-go/download"
+	"github.com/bookstore-go/utils"
+)
+
+// hand-written responses in testdata/http, in the format saved by utils.RecordTransport
+const FixturesDir = "testdata/http"
+
+func replayFixtures(t *testing.T) {
+	old_factory := utils.SetHttpRequestFactory(utils.NewReplayRequestFactory(FixturesDir))
+	t.Cleanup(func() {
+		utils.SetHttpRequestFactory(old_factory)
+	})
+}
+
+func TestGoogleFileInfo(t *testing.T) {
+	replayFixtures(t)
+	goog := &download.GoogleClient{Data: &download.StorageData{Token: "token"}}
+
+	info, err := goog.ReadFileInfo("1PdfId")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if info.Name != "gopl.pdf" || info.Size != 1048576 {
+		t.Fatalf("Unexpected file info %#v\n", info)
+	}
+
+	got := goog.DownloadUrl("1PdfId")
+	if !strings.Contains(got, "/files/1PdfId?") || !strings.Contains(got, "alt=media") || !strings.Contains(got, "supportsAllDrives=true") {
+		t.Fatalf("Unexpected download url '%s'\n", got)
+	}
+}
+
+func TestGoogleExportUrl(t *testing.T) {
+	replayFixtures(t)
+	goog := &download.GoogleClient{Data: &download.StorageData{Token: "token"}}
+
+	// documents are exported to epub by default
+	info, err := goog.ReadFileInfo("1DocId")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if info.Name != "Go Notes.epub" {
+		t.Fatalf("Exported file name mismatch (want 'Go Notes.epub', got '%s')\n", info.Name)
+	}
+	got := goog.FileUrl(info)
+	want := "https://www.googleapis.com/drive/v3/files/1DocId/export?mimeType=application%2Fepub%2Bzip"
+	if got != want {
+		t.Fatalf("Export url mismatch (want '%s', got '%s')\n", want, got)
+	}
+
+	goog.Data.Store.Export = map[string]string{"application/vnd.google-apps.document": "application/pdf"}
+	info, _ = goog.ReadFileInfo("1DocId")
+	got = goog.FileUrl(info)
+	want = "https://www.googleapis.com/drive/v3/files/1DocId/export?mimeType=application%2Fpdf"
+	if got != want || info.Name != "Go Notes.pdf" {
+		t.Fatalf("Export url mismatch (want '%s', got '%s', '%s')\n", want, got, info.Name)
+	}
+
+	// binary files are downloaded
+	info, _ = goog.ReadFileInfo("1PdfId")
+	if got = goog.FileUrl(info); !strings.Contains(got, "/files/1PdfId?") || !strings.Contains(got, "alt=media") {
+		t.Fatalf("Download url expected for pdf file (got '%s')\n", got)
+	}
+}
+
+func TestGoogleAbusiveFile(t *testing.T) {
+	replayFixtures(t)
+	goog := &download.GoogleClient{Data: &download.StorageData{Token: "token"}}
+
+	// metadata is readable, download of content is refused
+	info, err := goog.ReadFileInfo("1AbuseId")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	req := utils.CreateRequest(goog.FileUrl(info))
+	resp := req.GetStream()
+	if resp == nil {
+		t.Fatalf("Replay of %s failed: %s\n", goog.FileUrl(info), req.Error())
+	}
+	defer resp.Body().Close()
+	if resp.StatusCode() != 403 {
+		t.Fatalf("Status 403 expected (got %d)\n", resp.StatusCode())
+	}
+	err = goog.DownloadError(resp)
+	if !strings.Contains(err.Error(), "acknowledge-abuse") {
+		t.Fatalf("Error should tell about acknowledge-abuse setting (got '%s')\n", err)
+	}
+}
+
+func TestOneDriveItemPath(t *testing.T) {
+	od := &download.OneDriveClient{Data: &download.StorageData{}}
+
+	tests := []struct {
+		file_id string
+		want    string
+	}{
+		{"0123ABC", "/me/drive/items/0123ABC"},
+		{"a.b.0123ABC", "/me/drive/items/0123ABC"},
+		{"D4648F06C91D9D3D!54927", "/drives/D4648F06C91D9D3D/items/D4648F06C91D9D3D!54927"},
+		{"/drives/b!xyz/items/01ABC/", "/drives/b!xyz/items/01ABC"},
+		{"https://1drv.ms/b/s!AkL2bOokStore", "/shares/u!aHR0cHM6Ly8xZHJ2Lm1zL2IvcyFBa0wyYk9va1N0b3Jl/driveItem"},
+	}
+	for _, test := range tests {
+		got := od.ItemPath(test.file_id)
+		if got != test.want {
+			t.Fatalf("Item path of '%s' mismatch (want '%s', got '%s')\n", test.file_id, test.want, got)
+		}
+	}
+}
+
+func TestOneDriveSharedLinkInfo(t *testing.T) {
+	replayFixtures(t)
+	od := &download.OneDriveClient{Data: &download.StorageData{Token: "token"}}
+
+	info, err := od.ReadFileInfo("https://1drv.ms/b/s!AkL2bOokStore")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if info.Name != "The Go Programming Language.epub" || info.Size != 2345678 || len(info.Hash) == 0 {
+		t.Fatalf("Unexpected file info %#v\n", info)
+	}
+}
+
+func TestBoxSharedLink(t *testing.T) {
+	replayFixtures(t)
+	bx := &download.BoxComClient{Data: &download.StorageData{Token: "token"}}
+	link := "https://app.box.com/s/concurrency-in-go"
+
+	info, err := bx.ReadFileInfo(link)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if got := bx.FileUrl(info); got != "https://api.box.com/2.0/files/12345/content/" {
+		t.Fatalf("Unexpected download url '%s'\n", got)
+	}
+	if bx.DownloadHeaders(link)["BoxApi"] != "shared_link="+link {
+		t.Fatalf("BoxApi header not set for shared link (got %#v)\n", bx.DownloadHeaders(link))
+	}
+	if bx.DownloadHeaders("12345") != nil {
+		t.Fatal("No header expected for file id\n")
+	}
+
+	// shared link not resolved: no download url
+	old_factory := utils.SetHttpRequestFactory(utils.NewReplayRequestFactory(t.TempDir()))
+	defer utils.SetHttpRequestFactory(old_factory)
+	if _, err = bx.ReadFileInfo(link); err == nil {
+		t.Fatal("Error should not be null\n")
+	}
+}
+
+// PEM file of a new RSA key in PKCS1 or PKCS8 format
+func writePrivateKey(t *testing.T, key *rsa.PrivateKey, pkcs8 bool) string {
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if pkcs8 {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("Error should be null (got %s)\n", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	file_path := filepath.Join(t.TempDir(), "box.pem")
+	if err := ioutil.WriteFile(file_path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	return file_path
+}
+
+func TestBoxJwtAssertion(t *testing.T) {
+	key, err := rsa.GenerateKey(crand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+
+	for _, pkcs8 := range []bool{false, true} {
+		store := config.FileStore{ClientId: "jwtapp", AuthMode: "jwt", SubjectId: "4242", KeyId: "key1", PrivateKey: writePrivateKey(t, key, pkcs8)}
+		bx := &download.BoxComClient{Data: &download.StorageData{Store: store}}
+
+		assertion, err := bx.JwtAssertion()
+		if err != nil {
+			t.Fatalf("Error should be null (got %s)\n", err)
+		}
+		parts := strings.Split(assertion, ".")
+		if len(parts) != 3 {
+			t.Fatalf("JWT should have 3 parts (got %d)\n", len(parts))
+		}
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Fatalf("Error should be null (got %s)\n", err)
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Fatalf("Signature should be valid (got %s)\n", err)
+		}
+
+		var header map[string]string
+		var claims map[string]interface{}
+		for i, v := range []interface{}{&header, &claims} {
+			content, err := base64.RawURLEncoding.DecodeString(parts[i])
+			if err != nil {
+				t.Fatalf("Error should be null (got %s)\n", err)
+			}
+			if err = json.Unmarshal(content, v); err != nil {
+				t.Fatalf("Error should be null (got %s)\n", err)
+			}
+		}
+		if header["alg"] != "RS256" || header["kid"] != "key1" {
+			t.Fatalf("Unexpected JWT header %v\n", header)
+		}
+		if claims["iss"] != "jwtapp" || claims["sub"] != "4242" || claims["box_sub_type"] != "enterprise" ||
+			claims["aud"] != "https://api.box.com/oauth2/token" || len(claims["jti"].(string)) == 0 {
+			t.Fatalf("Unexpected JWT claims %v\n", claims)
+		}
+		if exp := int64(claims["exp"].(float64)); exp <= time.Now().Unix() || exp > time.Now().Add(time.Minute).Unix() {
+			t.Fatalf("JWT should expire within a minute (got %d)\n", exp)
+		}
+	}
+
+	// encrypted keys are refused
+	file_path := filepath.Join(t.TempDir(), "encrypted.pem")
+	ioutil.WriteFile(file_path, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{0}}), 0600)
+	bx := &download.BoxComClient{Data: &download.StorageData{Store: config.FileStore{PrivateKey: file_path}}}
+	if _, err = bx.JwtAssertion(); err == nil || !strings.Contains(err.Error(), "openssl") {
+		t.Fatalf("Encrypted key should be refused (got %v)\n", err)
+	}
+}
+
+func TestBoxRenewToken(t *testing.T) {
+	replayFixtures(t)
+	key, err := rsa.GenerateKey(crand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+
+	tests := []struct {
+		name          string
+		store         config.FileStore
+		refresh_token string
+		want_token    string
+		want_refresh  string
+	}{
+		{"refresh", config.FileStore{ClientId: "boxapp", Secret: "secret"}, "refresh-token", "refreshed-token", "new-refresh-token"},
+		{"ccg", config.FileStore{ClientId: "ccgapp", Secret: "secret", AuthMode: "ccg", SubjectId: "4242"}, "", "ccg-token", ""},
+		// expired refresh token is dropped, client credentials are used
+		{"refresh then ccg", config.FileStore{ClientId: "ccgapp", Secret: "secret", AuthMode: "ccg", SubjectId: "4242"}, "expired-token", "ccg-token", ""},
+		{"jwt", config.FileStore{ClientId: "jwtapp", Secret: "secret", AuthMode: "jwt", SubjectId: "4242", PrivateKey: writePrivateKey(t, key, false)}, "", "jwt-token", ""},
+	}
+	for _, test := range tests {
+		data := &download.StorageData{Store: test.store, RefreshToken: test.refresh_token}
+		bx := &download.BoxComClient{Data: data}
+
+		if err := bx.RenewToken(); err != nil {
+			t.Fatalf("%s: error should be null (got %s)\n", test.name, err)
+		}
+		if data.Token != test.want_token || data.RefreshToken != test.want_refresh {
+			t.Fatalf("%s: token mismatch (want '%s'/'%s', got '%s'/'%s')\n", test.name, test.want_token, test.want_refresh, data.Token, data.RefreshToken)
+		}
+		if data.TokenValidity <= time.Now().Unix() {
+			t.Fatalf("%s: token validity should be in the future (got %d)\n", test.name, data.TokenValidity)
+		}
+	}
+
+	bx := &download.BoxComClient{Data: &download.StorageData{Store: config.FileStore{ClientId: "boxapp"}}}
+	if err := bx.RenewToken(); err == nil {
+		t.Fatal("Renewal without refresh token or server authentication should fail\n")
+	}
+}
@@ -0,0 +1,241 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bookstore-go/utils"
+)
+
+// book details found by a metadata provider; empty fields are unknown
+type BookDetails struct {
+	Isbn      string
+	Title     string
+	Authors   string
+	Year      int
+	Publisher string
+	Language  string
+	Pages     int
+}
+
+// looks up book details in an online catalog
+type MetadataProvider interface {
+	Name() string
+	LookupIsbn(isbn string) (*BookDetails, error)
+	Search(title, authors string) (*BookDetails, error)
+}
+
+var ErrBookNotFound = errors.New("Book not found")
+
+func NewMetadataProvider(name, base_url string) (MetadataProvider, error) {
+	switch name {
+	case "", "openlibrary":
+		return NewOpenLibrary(base_url), nil
+	}
+	return nil, fmt.Errorf("Unknown metadata provider '%s'", name)
+}
+
+// looks up book by ISBN if known, else by title and authors; only empty fields of book are set.
+// Returns true if book was changed
+func EnrichBook(provider MetadataProvider, book *utils.Book) (bool, error) {
+
+	var details *BookDetails
+	var err error
+
+	if len(book.Isbn) > 0 {
+		details, err = provider.LookupIsbn(book.Isbn)
+	} else {
+		details, err = provider.Search(book.Title, book.Authors)
+		// a loose match would save details of another book
+		if err == nil && !MatchesBook(details, book.Title, book.Authors) {
+			err = ErrBookNotFound
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	set := func(field *string, value string) {
+		if len(*field) == 0 && len(value) > 0 {
+			*field = value
+			changed = true
+		}
+	}
+	set(&book.Isbn, details.Isbn)
+	set(&book.Publisher, details.Publisher)
+	set(&book.Language, details.Language)
+	if book.Pages == 0 && details.Pages > 0 {
+		book.Pages = details.Pages
+		changed = true
+	}
+	if book.Year == 0 && details.Year > 0 {
+		book.Year = details.Year
+		changed = true
+	}
+	return changed, nil
+}
+
+// details found by search are of book with title and authors: same normalized title,
+// an author in common if book has authors
+func MatchesBook(details *BookDetails, title, authors string) bool {
+	if utils.NormalizeTitle(details.Title) != utils.NormalizeTitle(title) {
+		return false
+	}
+	if len(utils.NormalizeAuthors(authors)) == 0 {
+		return true
+	}
+	found := make(map[string]bool)
+	for _, name := range strings.Split(utils.NormalizeAuthors(details.Authors), ",") {
+		found[name] = len(name) > 0
+	}
+	for _, name := range strings.Split(utils.NormalizeAuthors(authors), ",") {
+		if found[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// enriches book and saves it
+func EnrichBookId(provider MetadataProvider, bookId int) (*utils.Book, error) {
+	book, err := utils.GetBook(bookId)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := EnrichBook(provider, book)
+	if err != nil || !changed {
+		return book, err
+	}
+	return book, utils.UpdateBookDetails(book)
+}
+
+// ----------- OPEN LIBRARY -----------
+
+var OpenLibraryUrl = "https://openlibrary.org"
+
+// Open Library API: editions by ISBN (/isbn/<isbn>.json) and search (/search.json)
+type OpenLibrary struct {
+	BaseUrl string
+}
+
+func NewOpenLibrary(base_url string) *OpenLibrary {
+	if len(base_url) == 0 {
+		base_url = OpenLibraryUrl
+	}
+	return &OpenLibrary{strings.TrimSuffix(base_url, "/")}
+}
+
+type OpenLibraryEdition struct {
+	Title         string
+	Publishers    []string
+	PublishDate   string   `json:"publish_date"`
+	NumberOfPages int      `json:"number_of_pages"`
+	Isbn13        []string `json:"isbn_13"`
+	Languages     []struct {
+		Key string
+	}
+}
+
+type OpenLibrarySearch struct {
+	NumFound int `json:"numFound"`
+	Docs     []struct {
+		Title            string
+		AuthorName       []string `json:"author_name"`
+		FirstPublishYear int      `json:"first_publish_year"`
+		Isbn             []string
+		Publisher        []string
+		Language         []string
+		Pages            int `json:"number_of_pages_median"`
+	}
+}
+
+func (ol *OpenLibrary) Name() string {
+	return "openlibrary"
+}
+
+func (ol *OpenLibrary) LookupIsbn(isbn string) (*BookDetails, error) {
+
+	isbn, err := utils.NormalizeIsbn(isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	var edition OpenLibraryEdition
+	resp, err := GetJson(ol.BaseUrl+"/isbn/"+isbn+".json", nil, &edition)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() == 404 {
+		return nil, ErrBookNotFound
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("Open Library ISBN %s request returned status=%d", isbn, resp.StatusCode())
+	}
+
+	details := &BookDetails{Isbn: isbn, Title: edition.Title, Pages: edition.NumberOfPages, Year: PublishYear(edition.PublishDate)}
+	if len(edition.Isbn13) > 0 {
+		details.Isbn = edition.Isbn13[0]
+	}
+	if len(edition.Publishers) > 0 {
+		details.Publisher = edition.Publishers[0]
+	}
+	if len(edition.Languages) > 0 {
+		// "/languages/eng"
+		details.Language = edition.Languages[0].Key[strings.LastIndex(edition.Languages[0].Key, "/")+1:]
+	}
+	return details, nil
+}
+
+// first result matching title and authors, see MatchesBook
+func (ol *OpenLibrary) Search(title, authors string) (*BookDetails, error) {
+
+	args := map[string]string{"title": url.QueryEscape(title), "limit": "5"}
+	if len(authors) > 0 {
+		// first author is enough to narrow search
+		args["author"] = url.QueryEscape(strings.TrimSpace(strings.Split(authors, ",")[0]))
+	}
+
+	var search OpenLibrarySearch
+	resp, err := GetJson(utils.AddUrlArguments(ol.BaseUrl+"/search.json", args), nil, &search)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("Open Library search request returned status=%d", resp.StatusCode())
+	}
+
+	for _, doc := range search.Docs {
+		details := &BookDetails{Title: doc.Title, Year: doc.FirstPublishYear, Pages: doc.Pages, Authors: strings.Join(doc.AuthorName, ", ")}
+		if !MatchesBook(details, title, authors) {
+			continue
+		}
+		// prefer ISBN-13
+		for _, isbn := range doc.Isbn {
+			if len(details.Isbn) == 0 {
+				details.Isbn = isbn
+			}
+			if len(isbn) == 13 {
+				details.Isbn = isbn
+				break
+			}
+		}
+		if len(doc.Publisher) > 0 {
+			details.Publisher = doc.Publisher[0]
+		}
+		if len(doc.Language) > 0 {
+			details.Language = doc.Language[0]
+		}
+		return details, nil
+	}
+	return nil, ErrBookNotFound
+}
+
+// year of publish date such as "2015", "Oct 26, 2015" or "2015-10-26"
+func PublishYear(date string) int {
+	year, _ := strconv.Atoi(yearRegexp.FindString(date))
+	return year
+}
@@ -0,0 +1,130 @@
+package download_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bookstore-go/download"
+	"github.com/bookstore-go/utils"
+)
+
+// fake Open Library knowing one book
+func openLibraryServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch {
+		case req.URL.Path == "/isbn/9780134190440.json":
+			rw.Write([]byte(`{"title": "The Go Programming Language", "publishers": ["Addison-Wesley"], "publish_date": "Oct 26, 2015",
+				"number_of_pages": 380, "isbn_13": ["9780134190440"], "isbn_10": ["0134190440"], "languages": [{"key": "/languages/eng"}]}`))
+		case req.URL.Path == "/search.json" && req.URL.Query().Get("title") == "The Go Programming Language":
+			if req.URL.Query().Get("author") != "Alan A. A. Donovan" {
+				t.Errorf("Search should be done with first author (got '%s')\n", req.URL.Query().Get("author"))
+			}
+			rw.Write([]byte(`{"numFound": 1, "docs": [{"title": "The Go Programming Language", "author_name": ["Alan A. A. Donovan", "Brian W. Kernighan"],
+				"first_publish_year": 2015, "isbn": ["0134190440", "9780134190440"], "publisher": ["Addison-Wesley Professional"],
+				"language": ["eng"], "number_of_pages_median": 398}]}`))
+		case req.URL.Path == "/search.json" && req.URL.Query().Get("title") == "Go in Action":
+			// loose matches only
+			rw.Write([]byte(`{"numFound": 2, "docs": [{"title": "Go in Practice", "author_name": ["Matt Butcher"], "isbn": ["9781633430075"]},
+				{"title": "Go in Action", "author_name": ["Someone Else"], "isbn": ["9781617291784"], "publisher": ["Other"]}]}`))
+		case req.URL.Path == "/search.json":
+			rw.Write([]byte(`{"numFound": 0, "docs": []}`))
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenLibraryLookupIsbn(t *testing.T) {
+	ol := download.NewOpenLibrary(openLibraryServer(t).URL)
+
+	details, err := ol.LookupIsbn("978-0-13-419044-0")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if details.Publisher != "Addison-Wesley" || details.Language != "eng" || details.Pages != 380 || details.Year != 2015 {
+		t.Fatalf("Unexpected details %#v\n", details)
+	}
+
+	_, err = ol.LookupIsbn("0-8044-2957-X")
+	if err != download.ErrBookNotFound {
+		t.Fatalf("Book should not be found (got %v)\n", err)
+	}
+
+	_, err = ol.LookupIsbn("12345")
+	if err != utils.ErrInvalidIsbn {
+		t.Fatalf("ISBN should be invalid (got %v)\n", err)
+	}
+}
+
+func TestOpenLibrarySearch(t *testing.T) {
+	ol := download.NewOpenLibrary(openLibraryServer(t).URL)
+
+	details, err := ol.Search("The Go Programming Language", "Alan A. A. Donovan, Brian W. Kernighan")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if details.Isbn != "9780134190440" {
+		t.Fatalf("ISBN-13 should be preferred (got '%s')\n", details.Isbn)
+	}
+
+	_, err = ol.Search("Unknown book", "")
+	if err != download.ErrBookNotFound {
+		t.Fatalf("Book should not be found (got %v)\n", err)
+	}
+
+	// other title, same title by other authors
+	_, err = ol.Search("Go in Action", "William Kennedy, Brian Ketelsen")
+	if err != download.ErrBookNotFound {
+		t.Fatalf("Loose matches should be rejected (got %v)\n", err)
+	}
+	details, err = ol.Search("Go in Action", "")
+	if err != nil || details.Isbn != "9781617291784" {
+		t.Fatalf("Title match expected without authors (got %v, %v)\n", details, err)
+	}
+}
+
+func TestMatchesBook(t *testing.T) {
+	details := &download.BookDetails{Title: "The Go Programming Language", Authors: "Alan A. A. Donovan, Brian W. Kernighan"}
+	tests := []struct {
+		title   string
+		authors string
+		want    bool
+	}{
+		{"Go programming language (2nd edition)", "Brian Kernighan", true},
+		{"The Go Programming Language", "", true},
+		{"The Go Programming Language", "Rob Pike", false},
+		{"Go Programming", "Alan Donovan", false},
+	}
+	for _, test := range tests {
+		if got := download.MatchesBook(details, test.title, test.authors); got != test.want {
+			t.Fatalf("Match of '%s' by '%s' should be %v\n", test.title, test.authors, test.want)
+		}
+	}
+}
+
+func TestEnrichBook(t *testing.T) {
+	ol := download.NewOpenLibrary(openLibraryServer(t).URL)
+
+	// search by title and authors, language already known
+	book := &utils.Book{Title: "The Go Programming Language", Authors: "Alan A. A. Donovan, Brian W. Kernighan", Language: "en"}
+	changed, err := download.EnrichBook(ol, book)
+	if err != nil || !changed {
+		t.Fatalf("Book should be changed (got %v, %v)\n", changed, err)
+	}
+	if book.Isbn != "9780134190440" || book.Publisher != "Addison-Wesley Professional" || book.Pages != 398 || book.Year != 2015 {
+		t.Fatalf("Unexpected book %#v\n", book)
+	}
+	if book.Language != "en" {
+		t.Fatalf("Language should not be overwritten (got '%s')\n", book.Language)
+	}
+
+	// lookup by ISBN, nothing left to fill
+	changed, err = download.EnrichBook(ol, book)
+	if err != nil || changed {
+		t.Fatalf("Book should not be changed (got %v, %v)\n", changed, err)
+	}
+}
@@ -1,85 +1,247 @@
 package download
 
 import (
-	"github.com/bookstore-go/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"net/url"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
 	"path"
+	"strconv"
+	"strings"
+
+	"github.com/bookstore-go/config"
+	_ "golang.org/x/image/webp"
 )
 
-func SaveImage(url string, content []byte) {
+// Covers are stored by content hash:
+//
+//	<dir>/objects/<hash[:2]>/<hash>.<format>  original image
+//	<dir>/thumbs/<hash>_<width>.png           thumbnails, generated on demand
+//	<dir>/books/<book id>                     name of cover object of book
+type CoverStore struct {
+	Dir string
+}
+
+type Cover struct {
+	BookId int
+	Hash   string
+	Format string
+	Path   string
+}
+
+var ErrUnknownFormat = errors.New("Unknown image format")
+var ErrNoCover = errors.New("Book has no cover")
+var ErrEmptyImage = errors.New("Image is empty")
+
+func NewCoverStore(dir string) *CoverStore {
+	return &CoverStore{dir}
+}
+
+// store in working directory
+func Covers() *CoverStore {
+	return NewCoverStore(path.Join(config.GetConfig().Dirs.Workdir, "covers"))
+}
 
-	var folder string
-	var err error
-	pattern := fmt.Sprintf("img/(.+)/.+")
+// image format from content signature: "jpeg", "png", "gif" or "webp"
+func ImageFormat(content []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xff, 0xd8, 0xff}):
+		return "jpeg", nil
+	case bytes.HasPrefix(content, []byte("\x89PNG\r\n\x1a\n")):
+		return "png", nil
+	case bytes.HasPrefix(content, []byte("GIF87a")) || bytes.HasPrefix(content, []byte("GIF89a")):
+		return "gif", nil
+	case len(content) >= 12 && string(content[:4]) == "RIFF" && string(content[8:12]) == "WEBP":
+		return "webp", nil
+	}
+	return "", ErrUnknownFormat
+}
 
-	folder, err = utils.Extract_string(pattern, url)
+// stores cover of book
+func (store *CoverStore) SaveCover(bookId int, content []byte) (*Cover, error) {
 
+	format, err := ImageFormat(content)
 	if err != nil {
-		fmt.Printf("No directory found for url: %s\n", url)
-		return
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	object := hash + "." + format
+
+	object_path := store.objectPath(object)
+	if _, err = os.Stat(object_path); os.IsNotExist(err) {
+		err = os.MkdirAll(path.Dir(object_path), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = ioutil.WriteFile(object_path, content, 0644)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	folder, err = utils.UnescapeUrlString(folder)
+	err = os.MkdirAll(path.Join(store.Dir, "books"), 0755)
 	if err != nil {
-		fmt.Printf("error unescaping url %s", folder)
-		return
+		return nil, err
 	}
+	err = ioutil.WriteFile(path.Join(store.Dir, "books", strconv.Itoa(bookId)), []byte(object), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cover{bookId, hash, format, object_path}, nil
+}
 
-	err = utils.Create_folder(folder)
+// cover of book; ErrNoCover if book has no cover in store
+func (store *CoverStore) GetCover(bookId int) (*Cover, error) {
+
+	content, err := ioutil.ReadFile(path.Join(store.Dir, "books", strconv.Itoa(bookId)))
+	if os.IsNotExist(err) {
+		return nil, ErrNoCover
+	}
 	if err != nil {
-		fmt.Printf("create folder failed %s\n", folder)
+		return nil, err
+	}
+
+	object := strings.TrimSpace(string(content))
+	dot := strings.LastIndex(object, ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("Invalid cover object '%s' for book %d", object, bookId)
 	}
+	return &Cover{bookId, object[:dot], object[dot+1:], store.objectPath(object)}, nil
+}
 
-	file_path := path.Join(folder, "img.png")
-	fmt.Printf("image file= %s\n", file_path)
+func (store *CoverStore) LoadImage(bookId int) (image.Image, error) {
+	cover, err := store.GetCover(bookId)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(cover.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-	n, err := utils.Save_file(file_path, content)
-	if err == nil {
-		fmt.Printf("File %s OK: %d bytes written\n", file_path, n)
-	} else {
-		fmt.Printf("%s\n", err)
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot decode %s cover of book %d: %v", cover.Format, bookId, err)
 	}
+	if img.Bounds().Empty() {
+		return nil, ErrEmptyImage
+	}
+	return img, nil
 }
 
-func DownloadImage(url_page string) {
-	var err error
+// png thumbnail of book cover, width pixels wide; returns thumbnail file path
+func (store *CoverStore) Thumbnail(bookId int, width int) (string, error) {
 
-	req := utils.CreateRequest(url_page)
-	resp := req.Get()
-	if resp == nil {
-		fmt.Printf("Content not found at %s\n%s\n", url_page, req.Error())
-		return
+	cover, err := store.GetCover(bookId)
+	if err != nil {
+		return "", err
 	}
 
-	url_img, err := utils.GetImageUrl(resp.Content())
+	thumb_path := path.Join(store.Dir, "thumbs", fmt.Sprintf("%s_%d.png", cover.Hash, width))
+	if _, err = os.Stat(thumb_path); err == nil {
+		return thumb_path, nil
+	}
 
+	img, err := store.LoadImage(bookId)
 	if err != nil {
-		fmt.Printf("Image url cannot be extracted from page. Request page got status %d\n", resp.StatusCode())
-		return
+		return "", err
 	}
-	oUrl, err := url.Parse(url_page)
-	if err == nil {
-		oUrlImg, err := url.Parse(url_img)
 
-		if err == nil {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return "", ErrEmptyImage
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	thumb, err := ResizeImage(img, width, height)
+	if err != nil {
+		return "", err
+	}
 
-			oUrl.Path = "/books/" + oUrlImg.Path
-			url_img = oUrl.String()
+	err = os.MkdirAll(path.Dir(thumb_path), 0755)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Create(thumb_path)
+	if err != nil {
+		return "", err
+	}
+	err = png.Encode(f, thumb)
+	f.Close()
+	if err != nil {
+		os.Remove(thumb_path)
+		return "", err
+	}
+	return thumb_path, nil
+}
 
-		} else {
-			fmt.Println("Image url cannot be parsed " + url_img)
-			return
-		}
-	} else {
-		fmt.Printf("Cannot parse url imaget: %s -- err: %s\n", url_img, err)
-		return
+// cover of merged duplicate book becomes cover of kept book if it has none, pointer of duplicate is removed
+func (store *CoverStore) MergeCover(keepId, duplicateId int) error {
+	dup_path := path.Join(store.Dir, "books", strconv.Itoa(duplicateId))
+	if _, err := os.Stat(dup_path); os.IsNotExist(err) {
+		return nil
+	}
+	_, err := store.GetCover(keepId)
+	if err == ErrNoCover {
+		return os.Rename(dup_path, path.Join(store.Dir, "books", strconv.Itoa(keepId)))
+	}
+	if err != nil {
+		return err
 	}
-	req = utils.CreateRequest(url_img)
-	resp = req.Get()
-	if resp == nil {
-		fmt.Printf("Content not found at: %s\n%s\n", url_img, req.Error())
-		return
+	return os.Remove(dup_path)
+}
+
+func (store *CoverStore) objectPath(object string) string {
+	return path.Join(store.Dir, "objects", object[:2], object)
+}
+
+// scales image to width x height; each pixel is the average of source pixels it covers.
+// ErrEmptyImage if source has no pixel
+func ResizeImage(src image.Image, width, height int) (*image.RGBA, error) {
+
+	bounds := src.Bounds()
+	src_w, src_h := bounds.Dx(), bounds.Dy()
+	if src_w == 0 || src_h == 0 {
+		return nil, ErrEmptyImage
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y += 1 {
+		y0 := bounds.Min.Y + y*src_h/height
+		y1 := bounds.Min.Y + (y+1)*src_h/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x += 1 {
+			x0 := bounds.Min.X + x*src_w/width
+			x1 := bounds.Min.X + (x+1)*src_w/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy += 1 {
+				for sx := x0; sx < x1; sx += 1 {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n += 1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
 	}
-	SaveImage(url_img, resp.Content())
+	return dst, nil
 }
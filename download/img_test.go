@@ -0,0 +1,184 @@
+package download_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bookstore-go/download"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y += 1 {
+		for x := 0; x < width; x += 1 {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestImageFormat(t *testing.T) {
+	var png_buf, jpeg_buf bytes.Buffer
+	png.Encode(&png_buf, testImage(4, 4))
+	jpeg.Encode(&jpeg_buf, testImage(4, 4), nil)
+
+	tests := []struct {
+		content []byte
+		want    string
+	}{
+		{png_buf.Bytes(), "png"},
+		{jpeg_buf.Bytes(), "jpeg"},
+		{[]byte("GIF89a......"), "gif"},
+		{[]byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "webp"},
+	}
+	for _, test := range tests {
+		got, err := download.ImageFormat(test.content)
+		if err != nil || got != test.want {
+			t.Fatalf("Format mismatch (want '%s', got '%s', %v)\n", test.want, got, err)
+		}
+	}
+
+	if _, err := download.ImageFormat([]byte("<html>")); err != download.ErrUnknownFormat {
+		t.Fatalf("ErrUnknownFormat expected (got %v)\n", err)
+	}
+}
+
+func TestWebpCover(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/cover.webp")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	store := download.NewCoverStore(t.TempDir())
+	cover, err := store.SaveCover(1, content)
+	if err != nil || cover.Format != "webp" {
+		t.Fatalf("WebP cover should be saved (got %#v, %v)\n", cover, err)
+	}
+
+	img, err := store.LoadImage(1)
+	if err != nil {
+		t.Fatalf("WebP cover should be decoded (got %s)\n", err)
+	}
+	thumb_path, err := store.Thumbnail(1, 20)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	f, err := os.Open(thumb_path)
+	if err != nil {
+		t.Fatalf("Thumbnail not found (%s)\n", err)
+	}
+	thumb, err := png.Decode(f)
+	f.Close()
+	if err != nil || thumb.Bounds().Dx() != 20 {
+		t.Fatalf("Thumbnail of %v image should be 20 pixels wide (got %v, %v)\n", img.Bounds(), thumb.Bounds(), err)
+	}
+}
+
+func TestCoverStore(t *testing.T) {
+	store := download.NewCoverStore(t.TempDir())
+
+	if _, err := store.GetCover(1); err != download.ErrNoCover {
+		t.Fatalf("ErrNoCover expected (got %v)\n", err)
+	}
+
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, testImage(200, 300), nil)
+
+	saved, err := store.SaveCover(1, buf.Bytes())
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	// same content is stored once
+	other, err := store.SaveCover(2, buf.Bytes())
+	if err != nil || other.Path != saved.Path {
+		t.Fatalf("Same content should share object (got '%s' and '%s')\n", saved.Path, other.Path)
+	}
+
+	cover, err := store.GetCover(1)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if cover.Format != "jpeg" || cover.Hash != saved.Hash || cover.Path != saved.Path {
+		t.Fatalf("Cover mismatch (want %#v, got %#v)\n", saved, cover)
+	}
+
+	thumb_path, err := store.Thumbnail(1, 20)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	f, err := os.Open(thumb_path)
+	if err != nil {
+		t.Fatalf("Thumbnail not found (%s)\n", err)
+	}
+	thumb, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Thumbnail should be a png (got %s)\n", err)
+	}
+	if thumb.Bounds().Dx() != 20 || thumb.Bounds().Dy() != 30 {
+		t.Fatalf("Thumbnail should be 20x30 (got %v)\n", thumb.Bounds())
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	got, err := download.ResizeImage(testImage(10, 10), 2, 1)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if got.Bounds().Dx() != 2 || got.Bounds().Dy() != 1 {
+		t.Fatalf("Resized image should be 2x1 (got %v)\n", got.Bounds())
+	}
+	left := got.RGBAAt(0, 0)
+	right := got.RGBAAt(1, 0)
+	if left.R != 255 || left.B != 0 || right.R != 0 || right.B != 255 {
+		t.Fatalf("Unexpected colors left=%v right=%v\n", left, right)
+	}
+
+	if _, err = download.ResizeImage(image.NewRGBA(image.Rect(0, 0, 0, 10)), 2, 1); err != download.ErrEmptyImage {
+		t.Fatalf("ErrEmptyImage expected (got %v)\n", err)
+	}
+}
+
+func TestMergeCover(t *testing.T) {
+	store := download.NewCoverStore(t.TempDir())
+
+	var buf1, buf2 bytes.Buffer
+	jpeg.Encode(&buf1, testImage(20, 30), nil)
+	png.Encode(&buf2, testImage(20, 30))
+	saved1, _ := store.SaveCover(1, buf1.Bytes())
+	saved2, _ := store.SaveCover(2, buf2.Bytes())
+
+	// book 3 has no cover: gets cover of book 1
+	if err := store.MergeCover(3, 1); err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if cover, err := store.GetCover(3); err != nil || cover.Hash != saved1.Hash {
+		t.Fatalf("Cover of merged book expected (got %v, %v)\n", cover, err)
+	}
+	if _, err := store.GetCover(1); err != download.ErrNoCover {
+		t.Fatalf("Merged book should have no cover (got %v)\n", err)
+	}
+
+	// book 3 keeps its cover
+	if err := store.MergeCover(3, 2); err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if cover, err := store.GetCover(3); err != nil || cover.Hash == saved2.Hash {
+		t.Fatalf("Cover of kept book expected (got %v, %v)\n", cover, err)
+	}
+	if _, err := store.GetCover(2); err != download.ErrNoCover {
+		t.Fatalf("Merged book should have no cover (got %v)\n", err)
+	}
+	if err := store.MergeCover(3, 4); err != nil {
+		t.Fatalf("Book without cover should be merged (got %s)\n", err)
+	}
+}
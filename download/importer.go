@@ -0,0 +1,201 @@
+package download
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bookstore-go/utils"
+	"golang.org/x/net/html"
+)
+
+type BookMetadata struct {
+	SourceUrl   string
+	Title       string
+	Authors     string
+	Year        int
+	Description string
+	CoverUrl    string
+	Subjects    []string
+}
+
+// extracts book metadata from pages of a web site
+type SiteAdapter interface {
+	Name() string
+	Match(page_url *url.URL) bool
+	Parse(page_url *url.URL, doc *html.Node) (*BookMetadata, error)
+}
+
+// adapters are tried in registration order
+var site_adapters = []SiteAdapter{&BookStoreSite{}}
+
+func RegisterSiteAdapter(adapter SiteAdapter) {
+	// keep generic book store adapter last
+	n := len(site_adapters)
+	site_adapters = append(site_adapters[:n-1], adapter, site_adapters[n-1])
+}
+
+func FindSiteAdapter(page_url *url.URL) (SiteAdapter, error) {
+	for _, adapter := range site_adapters {
+		if adapter.Match(page_url) {
+			return adapter, nil
+		}
+	}
+	return nil, fmt.Errorf("No site adapter for %s", page_url.Host)
+}
+
+func FetchBookMetadata(page_url string) (*BookMetadata, error) {
+
+	u, err := url.Parse(page_url)
+	if err != nil {
+		return nil, err
+	}
+	adapter, err := FindSiteAdapter(u)
+	if err != nil {
+		return nil, err
+	}
+
+	req := utils.CreateRequest(page_url)
+	resp := req.Get()
+	if resp == nil {
+		return nil, req.Error()
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("Page %s request returned status=%d", page_url, resp.StatusCode())
+	}
+
+	doc, err := html.Parse(bytes.NewReader(resp.Content()))
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := adapter.Parse(u, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", adapter.Name(), err)
+	}
+	meta.SourceUrl = page_url
+	return meta, nil
+}
+
+// fetches book page, saves book, subjects and cover; returns book id
+func ImportBook(page_url string) (int, error) {
+
+	meta, err := FetchBookMetadata(page_url)
+	if err != nil {
+		return 0, err
+	}
+
+	book := &utils.Book{Title: meta.Title, Authors: meta.Authors, Year: meta.Year, Description: meta.Description}
+	book_id, err := utils.UpsertBook(book)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range meta.Subjects {
+		subject_id, err := utils.UpsertSubject(name)
+		if err != nil {
+			return book_id, err
+		}
+		err = utils.AddBookSubject(book_id, subject_id)
+		if err != nil {
+			return book_id, err
+		}
+	}
+
+	// book is imported without cover if cover cannot be fetched or stored
+	if len(meta.CoverUrl) > 0 {
+		content, err := FetchCover(meta.CoverUrl)
+		if err == nil {
+			_, err = Covers().SaveCover(book_id, content)
+		}
+		if err != nil {
+			log.Printf("Cover %s of book %d not saved: %v\n", meta.CoverUrl, book_id, err)
+		}
+	}
+	return book_id, nil
+}
+
+func FetchCover(cover_url string) ([]byte, error) {
+	req := utils.CreateRequest(cover_url)
+	resp := req.Get()
+	if resp == nil {
+		return nil, req.Error()
+	}
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("Cover %s request returned status=%d", cover_url, resp.StatusCode())
+	}
+	return resp.Content(), nil
+}
+
+// ----------- BOOK STORE web site -----------
+
+// year of publication dates, also used by metadata providers
+var yearRegexp = regexp.MustCompile(`\d{4}`)
+
+// pages of book store web site: book record is in <table class='book_record'>
+type BookStoreSite struct{}
+
+func (site *BookStoreSite) Name() string {
+	return "book store"
+}
+
+func (site *BookStoreSite) Match(page_url *url.URL) bool {
+	return true
+}
+
+func (site *BookStoreSite) Parse(page_url *url.URL, doc *html.Node) (*BookMetadata, error) {
+
+	record := utils.FindNode(doc, utils.Element("table", "book_record"))
+	if record == nil {
+		return nil, errors.New("Book record not found in page")
+	}
+
+	meta := &BookMetadata{}
+
+	if title := utils.FindNode(record, utils.Element("span", "book_title")); title != nil {
+		meta.Title = utils.NodeText(title)
+	}
+	if len(meta.Title) == 0 {
+		return nil, errors.New("Book title not found in page")
+	}
+
+	if img := utils.FindNode(record, utils.Element("img", "book")); img != nil {
+		src, err := url.Parse(utils.Attr(img, "src"))
+		if err == nil {
+			meta.CoverUrl = page_url.ResolveReference(src).String()
+		}
+	}
+
+	for _, tag := range utils.FindNodes(record, utils.Element("span", "tag")) {
+		meta.Subjects = append(meta.Subjects, utils.NodeText(tag))
+	}
+
+	// <div class='book'>Auteur: <span>Julitta Korol</span></div>
+	for _, div := range utils.FindNodes(record, utils.Element("div", "book")) {
+		label := strings.ToLower(utils.NodeText(div))
+		value := utils.FindNode(div, utils.Element("span", ""))
+		if value == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(label, "auteur:") || strings.HasPrefix(label, "author:") || strings.HasPrefix(label, "authors:"):
+			meta.Authors = utils.NodeText(value)
+		case strings.HasPrefix(label, "parution:") || strings.HasPrefix(label, "year:") || strings.HasPrefix(label, "published:"):
+			meta.Year, _ = strconv.Atoi(yearRegexp.FindString(utils.NodeText(value)))
+		}
+	}
+
+	if descr := utils.FindNode(record, utils.Element("span", "book_descr")); descr != nil {
+		if scrollable := utils.FindNode(descr, utils.Element("div", "scrollable")); scrollable != nil {
+			descr = scrollable
+		}
+		meta.Description = utils.InnerHtml(descr)
+	}
+
+	return meta, nil
+}
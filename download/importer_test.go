@@ -0,0 +1,83 @@
+package download_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/download"
+	"golang.org/x/net/html"
+)
+
+func TestFetchBookMetadata(t *testing.T) {
+	replayFixtures(t)
+
+	meta, err := download.FetchBookMetadata("http://books.test/books/book.php?id=6363")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+
+	if meta.Title != "The Go Programming Language" {
+		t.Fatalf("Title mismatch (got '%s')\n", meta.Title)
+	}
+	if meta.Authors != "Alan A. A. Donovan, Brian W. Kernighan" {
+		t.Fatalf("Authors mismatch (got '%s')\n", meta.Authors)
+	}
+	if meta.Year != 2015 {
+		t.Fatalf("Year mismatch (got %d)\n", meta.Year)
+	}
+	want_cover := "http://books.test/books/img/%5Bgo-programming-language%5D/img.png"
+	if meta.CoverUrl != want_cover {
+		t.Fatalf("Cover url mismatch (want '%s', got '%s')\n", want_cover, meta.CoverUrl)
+	}
+	if len(meta.Subjects) != 2 || meta.Subjects[0] != "PROGRAMMING" || meta.Subjects[1] != "GO" {
+		t.Fatalf("Subjects mismatch (got %q)\n", meta.Subjects)
+	}
+	if !strings.HasPrefix(meta.Description, "<h3>EPUB Format</h3>") || !strings.Contains(meta.Description, "<li>Concurrency</li>") {
+		t.Fatalf("Description should keep html (got '%s')\n", meta.Description)
+	}
+}
+
+func TestFetchCover(t *testing.T) {
+	replayFixtures(t)
+
+	content, err := download.FetchCover("http://books.test/books/img/%5Bgo-programming-language%5D/img.png")
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+
+	format, err := download.ImageFormat(content)
+	if err != nil || format != "png" {
+		t.Fatalf("Cover should be a png image (got '%s', %v)\n", format, err)
+	}
+}
+
+type testSite struct{}
+
+func (site *testSite) Name() string {
+	return "test"
+}
+
+func (site *testSite) Match(page_url *url.URL) bool {
+	return page_url.Host == "site.test"
+}
+
+func (site *testSite) Parse(page_url *url.URL, doc *html.Node) (*download.BookMetadata, error) {
+	return &download.BookMetadata{}, nil
+}
+
+func TestFindSiteAdapter(t *testing.T) {
+	download.RegisterSiteAdapter(&testSite{})
+
+	u, _ := url.Parse("http://site.test/book/1")
+	adapter, err := download.FindSiteAdapter(u)
+	if err != nil || adapter.Name() != "test" {
+		t.Fatalf("Test adapter expected for %s (got %#v, %v)\n", u, adapter, err)
+	}
+
+	u, _ = url.Parse("http://books.test/books/book.php?id=1")
+	adapter, err = download.FindSiteAdapter(u)
+	if err != nil || adapter.Name() != "book store" {
+		t.Fatalf("Book store adapter expected for %s (got %#v, %v)\n", u, adapter, err)
+	}
+}
@@ -3,8 +3,10 @@ module github.com/bookstore-go
 go 1.17
 
 require (
-	github.com/BurntSushi/toml v0.4.1 // indirect
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
-	github.com/rthornton128/goncurses v0.0.0-20210908011339-931b33a34c71 // indirect
-	golang.org/x/crypto v0.0.0-20211108221036-ceb1ce70b4fa // indirect
+	github.com/BurntSushi/toml v0.4.1
+	github.com/go-sql-driver/mysql v1.6.0
+	github.com/rthornton128/goncurses v0.0.0-20210908011339-931b33a34c71
+	golang.org/x/crypto v0.0.0-20211108221036-ceb1ce70b4fa
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.0.0-20211112202133-69e39bad7dc2
 )
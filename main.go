@@ -1,8 +1,11 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/bookstore-go/config"
 	"github.com/bookstore-go/console"
@@ -20,16 +23,41 @@ func main() {
 		panic("Error loading configuration: " + err.Error())
 	}
 
+	err = utils.ConfigureHttpClient(config.GetConfig().Http)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	err = utils.DbConnect(config.GetConfig().Database)
 
 	if err != nil {
 		log.Fatal("Cannot connect to database " + err.Error())
 	}
 
-	// connect to db to get file storage vendors
-	download.InitVendorsData()
+	err = console.LoadKeys(config.GetConfig().Keys)
 
-	console.TerminalLoop()
+	if err != nil {
+		log.Fatal("Invalid key bindings: " + err.Error())
+	}
+
+	err = console.LoadTheme(config.GetConfig().Theme)
+
+	if err != nil {
+		log.Fatal("Invalid theme: " + err.Error())
+	}
+
+	if flag.NArg() > 0 {
+		err = RunCommand(flag.Args())
+		if err != nil {
+			log.Println(err)
+		}
+	} else {
+		// connect to db to get file storage vendors
+		download.InitVendorsData()
+
+		console.TerminalLoop()
+	}
 
 	err = utils.DbClose()
 
@@ -38,3 +66,71 @@ func main() {
 	}
 
 }
+
+// commands run without terminal UI: bookstore-go [-c config] <command> [args...]
+func RunCommand(args []string) error {
+
+	switch args[0] {
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: import <book page url>...")
+		}
+		for _, page_url := range args[1:] {
+			id, err := download.ImportBook(page_url)
+			if err != nil {
+				log.Printf("Import %s failed: %v\n", page_url, err)
+			} else {
+				log.Printf("Imported %s: book %d\n", page_url, id)
+			}
+		}
+	case "enrich":
+		// enrich given books, or all books with missing details
+		enrich := config.GetConfig().Enrich
+		provider, err := download.NewMetadataProvider(enrich.Provider, enrich.Url)
+		if err != nil {
+			return err
+		}
+		var book_ids []int
+		for _, arg := range args[1:] {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("usage: enrich [book id]...")
+			}
+			book_ids = append(book_ids, id)
+		}
+		if len(book_ids) == 0 {
+			books, err := utils.GetBooksToEnrich()
+			if err != nil {
+				return err
+			}
+			for _, book := range books {
+				book_ids = append(book_ids, book.Id)
+			}
+		}
+		for _, id := range book_ids {
+			book, err := download.EnrichBookId(provider, id)
+			if err != nil {
+				log.Printf("Enrich book %d failed: %v\n", id, err)
+			} else {
+				log.Printf("Enriched book %d: isbn=%s publisher=%s language=%s pages=%d\n", id, book.Isbn, book.Publisher, book.Language, book.Pages)
+			}
+		}
+	case "dedupe":
+		books, err := utils.GetBooks()
+		if err != nil {
+			return err
+		}
+		hashes, err := utils.GetFileHashes()
+		if err != nil {
+			return err
+		}
+		clusters := utils.FindDuplicates(books, hashes)
+		log.Printf("%d clusters of duplicate books found\n", len(clusters))
+		if len(clusters) > 0 {
+			console.DedupeLoop(clusters)
+		}
+	default:
+		return fmt.Errorf("Unknown command '%s'", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+-- several accounts per storage vendor: one FILE_STORE row per account,
+-- ACCOUNT is the account name in configuration ([Storage.Box.Accounts.<name>]),
+-- NULL for the default account of the vendor.
+-- mysql <database> < schema/migrations/001_file_store_account.sql
+
+ALTER TABLE FILE_STORE ADD COLUMN ACCOUNT VARCHAR(64) NULL;
@@ -0,0 +1,7 @@
+-- metadata filled by enrich command (ISBN lookup or title search), NULL until found
+
+ALTER TABLE BOOKS
+  ADD COLUMN ISBN VARCHAR(20) NULL,
+  ADD COLUMN PUBLISHER VARCHAR(255) NULL,
+  ADD COLUMN LANG VARCHAR(16) NULL,
+  ADD COLUMN PAGES INT NULL;
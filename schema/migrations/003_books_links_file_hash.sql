@@ -0,0 +1,4 @@
+-- sha256 of downloaded file, used to find duplicate books; NULL until file is downloaded
+
+ALTER TABLE BOOKS_LINKS ADD COLUMN FILE_HASH CHAR(64) NULL;
+CREATE INDEX BOOKS_LINKS_FILE_HASH ON BOOKS_LINKS (FILE_HASH);
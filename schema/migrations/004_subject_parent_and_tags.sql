@@ -0,0 +1,13 @@
+-- subject hierarchy: parent subject, NULL for root subjects
+
+ALTER TABLE IT_SUBJECT ADD COLUMN PARENT_ID INT NULL;
+CREATE INDEX IT_SUBJECT_PARENT ON IT_SUBJECT (PARENT_ID);
+
+-- free-form tags of books, lower case (see utils.NormalizeTags)
+
+CREATE TABLE BOOKS_TAGS (
+  BOOK_ID INT NOT NULL,
+  TAG VARCHAR(128) NOT NULL,
+  PRIMARY KEY (BOOK_ID, TAG),
+  KEY BOOKS_TAGS_TAG (TAG)
+);
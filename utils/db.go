@@ -4,21 +4,25 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// columns and tables added to the original schema are created by schema/migrations/*.sql
 type Db struct {
 	Connected        bool
 	DbObj            *sql.DB
 	ConnectionString string
 }
 
+// PARENT_ID of IT_SUBJECT is parent subject, NULL for root subjects
 type Subject struct {
-	Name    string
-	Id      uint
-	NbBooks uint
+	Name     string
+	Id       uint
+	NbBooks  uint
+	ParentId uint
 }
 
 type BookLine struct {
@@ -26,12 +30,17 @@ type BookLine struct {
 	Title string
 }
 
+// ISBN, PUBLISHER, LANG and PAGES columns of BOOKS are filled by metadata enrichment and may be NULL
 type Book struct {
 	Id          int
 	Title       string
 	Authors     string
 	Year        int
 	Description string
+	Isbn        string
+	Publisher   string
+	Language    string
+	Pages       int
 }
 
 type BookDownload struct {
@@ -42,6 +51,7 @@ type BookDownload struct {
 	FileName   string
 	Vendor     string
 	VendorCode string
+	Account    string
 }
 
 type Database struct {
@@ -55,6 +65,7 @@ type StorageVendor struct {
 	VendorName      string
 	VendorCode      string
 	StorageCapacity int
+	Account         string
 }
 
 var DatabaseObj Db
@@ -138,7 +149,7 @@ func GetSubjectBooks(subjectID int) ([]BookLine, error) {
 
 func GetBook(id int) (*Book, error) {
 	if DatabaseObj.Connected {
-		query := fmt.Sprintf("SELECT ID,TITLE,AUTHORS,YEAR, DESCR FROM BOOKS WHERE ID=%d", id)
+		query := fmt.Sprintf("SELECT ID,TITLE,AUTHORS,YEAR, DESCR,IFNULL(ISBN,''),IFNULL(PUBLISHER,''),IFNULL(LANG,''),IFNULL(PAGES,0) FROM BOOKS WHERE ID=%d", id)
 		row, err := DatabaseObj.DbObj.Query(query)
 
 		if err != nil {
@@ -146,7 +157,7 @@ func GetBook(id int) (*Book, error) {
 		}
 		book := &Book{}
 		if row.Next() {
-			row.Scan(&book.Id, &book.Title, &book.Authors, &book.Year, &book.Description)
+			row.Scan(&book.Id, &book.Title, &book.Authors, &book.Year, &book.Description, &book.Isbn, &book.Publisher, &book.Language, &book.Pages)
 		} else {
 			return nil, errors.New("Book not found")
 		}
@@ -159,7 +170,7 @@ func GetDownloadInfo(bookid int) (*BookDownload, error) {
 	if DatabaseObj.Connected {
 
 		//query := fmt.Sprintf("SELECT BOOK_ID,STORE_ID,FILE_ID,FILE_SIZE,FILE_NAME,VENDOR,VENDOR_CODE FROM BOOKS_LINKS,FILE_STORE WHERE BOOK_ID=%d", bookid)
-		query := fmt.Sprintf("SELECT BOOK_ID,STORE_ID,FILE_ID,FILE_SIZE,FILE_NAME,VENDOR,VENDOR_CODE FROM BOOKS_LINKS, FILE_STORE FS WHERE BOOK_ID=%d AND FS.ID=STORE_ID", bookid)
+		query := fmt.Sprintf("SELECT BOOK_ID,STORE_ID,FILE_ID,FILE_SIZE,FILE_NAME,VENDOR,VENDOR_CODE,IFNULL(ACCOUNT,'') FROM BOOKS_LINKS, FILE_STORE FS WHERE BOOK_ID=%d AND FS.ID=STORE_ID", bookid)
 		row, err := DatabaseObj.DbObj.Query(query)
 
 		if err != nil {
@@ -169,7 +180,7 @@ func GetDownloadInfo(bookid int) (*BookDownload, error) {
 		if row.Next() {
 			book_dl := &BookDownload{}
 
-			row.Scan(&book_dl.BookId, &book_dl.StorageId, &book_dl.FileId, &book_dl.FileSize, &book_dl.FileName, &book_dl.Vendor, &book_dl.VendorCode)
+			row.Scan(&book_dl.BookId, &book_dl.StorageId, &book_dl.FileId, &book_dl.FileSize, &book_dl.FileName, &book_dl.Vendor, &book_dl.VendorCode, &book_dl.Account)
 			return book_dl, nil
 		} else {
 			return nil, errors.New("Book download info not found")
@@ -180,7 +191,8 @@ func GetDownloadInfo(bookid int) (*BookDownload, error) {
 
 func GetVendors() ([]*StorageVendor, error) {
 	if DatabaseObj.Connected {
-		sql := "SELECT ID,VENDOR,VENDOR_CODE,STORAGE_CAPACITY FROM FILE_STORE"
+		// one FILE_STORE row per vendor account; ACCOUNT names the account in configuration
+		sql := "SELECT ID,VENDOR,VENDOR_CODE,STORAGE_CAPACITY,IFNULL(ACCOUNT,'') FROM FILE_STORE"
 		rows, err := DatabaseObj.DbObj.Query(sql)
 		if err != nil {
 			return nil, err
@@ -188,7 +200,7 @@ func GetVendors() ([]*StorageVendor, error) {
 		var vendors []*StorageVendor
 		for rows.Next() {
 			v := &StorageVendor{}
-			rows.Scan(&v.Id, &v.VendorName, &v.VendorCode, &v.StorageCapacity)
+			rows.Scan(&v.Id, &v.VendorName, &v.VendorCode, &v.StorageCapacity, &v.Account)
 			vendors = append(vendors, v)
 		}
 		return vendors, nil
@@ -196,6 +208,526 @@ func GetVendors() ([]*StorageVendor, error) {
 	return nil, errors.New("Not connected to DB")
 }
 
+// inserts book or updates book with same title and authors; returns book id.
+// Year and description of existing book are kept when book has none
+func UpsertBook(book *Book) (int, error) {
+	if DatabaseObj.Connected {
+		var id int
+		err := DatabaseObj.DbObj.QueryRow("SELECT ID FROM BOOKS WHERE TITLE=? AND AUTHORS=?", book.Title, book.Authors).Scan(&id)
+
+		if err == sql.ErrNoRows {
+			res, err := DatabaseObj.DbObj.Exec("INSERT INTO BOOKS (TITLE,AUTHORS,YEAR,DESCR) VALUES (?,?,?,?)", book.Title, book.Authors, book.Year, book.Description)
+			if err != nil {
+				return 0, err
+			}
+			last_id, err := res.LastInsertId()
+			book.Id = int(last_id)
+			return book.Id, err
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = DatabaseObj.DbObj.Exec("UPDATE BOOKS SET YEAR=COALESCE(NULLIF(?,0),YEAR), DESCR=COALESCE(NULLIF(?,''),DESCR) WHERE ID=?", book.Year, book.Description, id)
+		book.Id = id
+		return id, err
+	}
+	return 0, errors.New("Not connected to DB")
+}
+
+// returns id of subject, subject is created if not found
+func UpsertSubject(name string) (uint, error) {
+	if DatabaseObj.Connected {
+		var id uint
+		err := DatabaseObj.DbObj.QueryRow("SELECT ID FROM IT_SUBJECT WHERE NAME=?", name).Scan(&id)
+
+		if err == sql.ErrNoRows {
+			res, err := DatabaseObj.DbObj.Exec("INSERT INTO IT_SUBJECT (NAME) VALUES (?)", name)
+			if err != nil {
+				return 0, err
+			}
+			last_id, err := res.LastInsertId()
+			return uint(last_id), err
+		}
+		return id, err
+	}
+	return 0, errors.New("Not connected to DB")
+}
+
+func AddBookSubject(bookId int, subjectId uint) error {
+	if DatabaseObj.Connected {
+		_, err := DatabaseObj.DbObj.Exec("INSERT IGNORE INTO BOOKS_SUBJECTS_ASSOC (BOOK_ID,SUBJECT_ID) VALUES (?,?)", bookId, subjectId)
+		return err
+	}
+	return errors.New("Not connected to DB")
+}
+
+// books without ISBN, publisher, language or page count
+func GetBooksToEnrich() ([]*Book, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT ID,TITLE,AUTHORS,YEAR,IFNULL(ISBN,''),IFNULL(PUBLISHER,''),IFNULL(LANG,''),IFNULL(PAGES,0) FROM BOOKS " +
+			"WHERE IFNULL(ISBN,'')='' OR IFNULL(PUBLISHER,'')='' OR IFNULL(LANG,'')='' OR IFNULL(PAGES,0)=0")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var books []*Book
+		for rows.Next() {
+			book := &Book{}
+			err = rows.Scan(&book.Id, &book.Title, &book.Authors, &book.Year, &book.Isbn, &book.Publisher, &book.Language, &book.Pages)
+			if err != nil {
+				return nil, err
+			}
+			books = append(books, book)
+		}
+		return books, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+func UpdateBookDetails(book *Book) error {
+	if DatabaseObj.Connected {
+		_, err := DatabaseObj.DbObj.Exec("UPDATE BOOKS SET YEAR=?, ISBN=?, PUBLISHER=?, LANG=?, PAGES=? WHERE ID=?",
+			book.Year, book.Isbn, book.Publisher, book.Language, book.Pages, book.Id)
+		return err
+	}
+	return errors.New("Not connected to DB")
+}
+
+func GetBooks() ([]*Book, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT ID,TITLE,AUTHORS,YEAR,IFNULL(ISBN,''),IFNULL(PUBLISHER,''),IFNULL(LANG,''),IFNULL(PAGES,0) FROM BOOKS ORDER BY ID")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var books []*Book
+		for rows.Next() {
+			book := &Book{}
+			err = rows.Scan(&book.Id, &book.Title, &book.Authors, &book.Year, &book.Isbn, &book.Publisher, &book.Language, &book.Pages)
+			if err != nil {
+				return nil, err
+			}
+			books = append(books, book)
+		}
+		return books, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+// FILE_HASH of BOOKS_LINKS is sha256 of downloaded file, NULL until file is downloaded
+func SetFileHash(bookId, storeId int, hash string) error {
+	if DatabaseObj.Connected {
+		_, err := DatabaseObj.DbObj.Exec("UPDATE BOOKS_LINKS SET FILE_HASH=? WHERE BOOK_ID=? AND STORE_ID=?", hash, bookId, storeId)
+		return err
+	}
+	return errors.New("Not connected to DB")
+}
+
+// file hashes by book id
+func GetFileHashes() (map[int][]string, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT BOOK_ID,FILE_HASH FROM BOOKS_LINKS WHERE IFNULL(FILE_HASH,'')<>''")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		hashes := make(map[int][]string)
+		for rows.Next() {
+			var id int
+			var hash string
+			err = rows.Scan(&id, &hash)
+			if err != nil {
+				return nil, err
+			}
+			hashes[id] = append(hashes[id], hash)
+		}
+		return hashes, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+// runs fn in a transaction: committed if fn returns no error, rolled back otherwise
+func WithTransaction(fn func(tx *sql.Tx) error) error {
+	if DatabaseObj.Connected {
+		tx, err := DatabaseObj.DbObj.Begin()
+		if err != nil {
+			return err
+		}
+		err = fn(tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+	return errors.New("Not connected to DB")
+}
+
+// moves subjects, tags and file links of duplicate books to book keepId, then deletes duplicates.
+// A link of a duplicate on a store where kept book already has a file is deleted
+func MergeBooks(keepId int, duplicateIds []int) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		for _, id := range duplicateIds {
+			if id == keepId {
+				continue
+			}
+			// rows of duplicate already set for kept book are deleted, others are moved to kept book
+			_, err := tx.Exec("DELETE D FROM BOOKS_SUBJECTS_ASSOC D JOIN BOOKS_SUBJECTS_ASSOC K ON K.SUBJECT_ID=D.SUBJECT_ID AND K.BOOK_ID=? WHERE D.BOOK_ID=?", keepId, id)
+			if err == nil {
+				_, err = tx.Exec("UPDATE BOOKS_SUBJECTS_ASSOC SET BOOK_ID=? WHERE BOOK_ID=?", keepId, id)
+			}
+			if err == nil {
+				_, err = tx.Exec("DELETE D FROM BOOKS_TAGS D JOIN BOOKS_TAGS K ON K.TAG=D.TAG AND K.BOOK_ID=? WHERE D.BOOK_ID=?", keepId, id)
+			}
+			if err == nil {
+				_, err = tx.Exec("UPDATE BOOKS_TAGS SET BOOK_ID=? WHERE BOOK_ID=?", keepId, id)
+			}
+			if err == nil {
+				_, err = tx.Exec("DELETE D FROM BOOKS_LINKS D JOIN BOOKS_LINKS K ON K.STORE_ID=D.STORE_ID AND K.BOOK_ID=? WHERE D.BOOK_ID=?", keepId, id)
+			}
+			if err == nil {
+				_, err = tx.Exec("UPDATE BOOKS_LINKS SET BOOK_ID=? WHERE BOOK_ID=?", keepId, id)
+			}
+			if err == nil {
+				_, err = tx.Exec("DELETE FROM BOOKS WHERE ID=?", id)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// all subjects, with subjects without books
+func GetAllSubjects() ([]Subject, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT S.ID,S.NAME,COUNT(A.BOOK_ID) FROM IT_SUBJECT S LEFT JOIN BOOKS_SUBJECTS_ASSOC A ON A.SUBJECT_ID=S.ID GROUP BY S.ID,S.NAME ORDER BY S.NAME")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var subjects []Subject
+		for rows.Next() {
+			var sub Subject
+			err = rows.Scan(&sub.Id, &sub.Name, &sub.NbBooks)
+			if err != nil {
+				return nil, err
+			}
+			subjects = append(subjects, sub)
+		}
+		return subjects, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+// inserts book if book.Id is 0, updates title, authors, year and description otherwise
+func SaveBook(book *Book) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		return saveBook(tx, book)
+	})
+}
+
+// saves book and replaces its tags in one transaction
+func SaveBookWithTags(book *Book, tags []string) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		err := saveBook(tx, book)
+		if err != nil {
+			return err
+		}
+		return setBookTags(tx, book.Id, tags)
+	})
+}
+
+func saveBook(tx *sql.Tx, book *Book) error {
+	if book.Id == 0 {
+		res, err := tx.Exec("INSERT INTO BOOKS (TITLE,AUTHORS,YEAR,DESCR) VALUES (?,?,?,?)", book.Title, book.Authors, book.Year, book.Description)
+		if err != nil {
+			return err
+		}
+		last_id, err := res.LastInsertId()
+		book.Id = int(last_id)
+		return err
+	}
+	res, err := tx.Exec("UPDATE BOOKS SET TITLE=?, AUTHORS=?, YEAR=?, DESCR=? WHERE ID=?", book.Title, book.Authors, book.Year, book.Description, book.Id)
+	if err != nil {
+		return err
+	}
+	// no row affected is either unchanged or missing book
+	n, err := res.RowsAffected()
+	if err == nil && n == 0 {
+		var id int
+		err = tx.QueryRow("SELECT ID FROM BOOKS WHERE ID=?", book.Id).Scan(&id)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("Book %d not found", book.Id)
+		}
+	}
+	return err
+}
+
+func GetBookSubjects(bookId int) ([]Subject, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT S.ID,S.NAME FROM IT_SUBJECT S, BOOKS_SUBJECTS_ASSOC A WHERE A.SUBJECT_ID=S.ID AND A.BOOK_ID=? ORDER BY S.NAME", bookId)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var subjects []Subject
+		for rows.Next() {
+			var sub Subject
+			err = rows.Scan(&sub.Id, &sub.Name)
+			if err != nil {
+				return nil, err
+			}
+			subjects = append(subjects, sub)
+		}
+		return subjects, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+// replaces subjects of book
+func SetBookSubjects(bookId int, subjectIds []uint) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM BOOKS_SUBJECTS_ASSOC WHERE BOOK_ID=?", bookId)
+		if err != nil {
+			return err
+		}
+		for _, id := range subjectIds {
+			_, err = tx.Exec("INSERT INTO BOOKS_SUBJECTS_ASSOC (BOOK_ID,SUBJECT_ID) VALUES (?,?)", bookId, id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// file links of book, one by file store
+func GetBookLinks(bookId int) ([]*BookDownload, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT BOOK_ID,STORE_ID,FILE_ID,FILE_SIZE,FILE_NAME,VENDOR,VENDOR_CODE,IFNULL(ACCOUNT,'') FROM BOOKS_LINKS, FILE_STORE FS "+
+			"WHERE BOOK_ID=? AND FS.ID=STORE_ID ORDER BY STORE_ID", bookId)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var links []*BookDownload
+		for rows.Next() {
+			link := &BookDownload{}
+			err = rows.Scan(&link.BookId, &link.StorageId, &link.FileId, &link.FileSize, &link.FileName, &link.Vendor, &link.VendorCode, &link.Account)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, link)
+		}
+		return links, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+// inserts or updates link of book in file store; oldStoreId is previous store of link, 0 for a new link
+func SaveBookLink(link *BookDownload, oldStoreId int) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		if link.StorageId != oldStoreId {
+			var n int
+			err := tx.QueryRow("SELECT COUNT(*) FROM BOOKS_LINKS WHERE BOOK_ID=? AND STORE_ID=?", link.BookId, link.StorageId).Scan(&n)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("Book %d already has a link in store %d", link.BookId, link.StorageId)
+			}
+		}
+		if oldStoreId == 0 {
+			_, err := tx.Exec("INSERT INTO BOOKS_LINKS (BOOK_ID,STORE_ID,FILE_ID,FILE_SIZE,FILE_NAME) VALUES (?,?,?,?,?)",
+				link.BookId, link.StorageId, link.FileId, link.FileSize, link.FileName)
+			return err
+		}
+		// hash of downloaded file is obsolete if file changed
+		_, err := tx.Exec("UPDATE BOOKS_LINKS SET FILE_HASH=IF(FILE_ID=?,FILE_HASH,NULL), STORE_ID=?, FILE_ID=?, FILE_SIZE=?, FILE_NAME=? WHERE BOOK_ID=? AND STORE_ID=?",
+			link.FileId, link.StorageId, link.FileId, link.FileSize, link.FileName, link.BookId, oldStoreId)
+		return err
+	})
+}
+
+func DeleteBookLink(bookId, storeId int) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM BOOKS_LINKS WHERE BOOK_ID=? AND STORE_ID=?", bookId, storeId)
+		return err
+	})
+}
+
+// subjects tree with book counts rolled up through the tree
+func GetSubjectTree() ([]*SubjectNode, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT ID,NAME,IFNULL(PARENT_ID,0) FROM IT_SUBJECT")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var subjects []Subject
+		for rows.Next() {
+			var sub Subject
+			err = rows.Scan(&sub.Id, &sub.Name, &sub.ParentId)
+			if err != nil {
+				return nil, err
+			}
+			subjects = append(subjects, sub)
+		}
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+
+		assoc, err := DatabaseObj.DbObj.Query("SELECT SUBJECT_ID,BOOK_ID FROM BOOKS_SUBJECTS_ASSOC")
+		if err != nil {
+			return nil, err
+		}
+		defer assoc.Close()
+
+		books := make(map[uint][]int)
+		for assoc.Next() {
+			var subject_id uint
+			var book_id int
+			err = assoc.Scan(&subject_id, &book_id)
+			if err != nil {
+				return nil, err
+			}
+			books[subject_id] = append(books[subject_id], book_id)
+		}
+		return BuildSubjectTree(subjects, books), assoc.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+// parentId 0 makes subject a root subject
+func SetSubjectParent(subjectId, parentId uint) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		var parent interface{}
+		if parentId != 0 {
+			parent = parentId
+		}
+		_, err := tx.Exec("UPDATE IT_SUBJECT SET PARENT_ID=? WHERE ID=?", parent, subjectId)
+		return err
+	})
+}
+
+// distinct books of subjects
+func GetSubjectsBooks(subjectIds []uint) ([]BookLine, error) {
+	if DatabaseObj.Connected {
+		if len(subjectIds) == 0 {
+			return nil, nil
+		}
+		args := make([]interface{}, len(subjectIds))
+		for i, id := range subjectIds {
+			args[i] = id
+		}
+		query := "SELECT DISTINCT B.ID,B.TITLE FROM BOOKS B, BOOKS_SUBJECTS_ASSOC WHERE BOOK_ID=B.ID AND SUBJECT_ID IN (?" + strings.Repeat(",?", len(subjectIds)-1) + ") ORDER BY B.TITLE"
+		return queryBookLines(query, args...)
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+func queryBookLines(query string, args ...interface{}) ([]BookLine, error) {
+	rows, err := DatabaseObj.DbObj.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []BookLine
+	for rows.Next() {
+		var line BookLine
+		err = rows.Scan(&line.Id, &line.Title)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, line)
+	}
+	return books, rows.Err()
+}
+
+// BOOKS_TAGS (BOOK_ID, TAG) holds free-form tags of books
+func GetTags() ([]Tag, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT TAG,COUNT(BOOK_ID) FROM BOOKS_TAGS GROUP BY TAG ORDER BY TAG")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var tags []Tag
+		for rows.Next() {
+			var tag Tag
+			err = rows.Scan(&tag.Name, &tag.NbBooks)
+			if err != nil {
+				return nil, err
+			}
+			tags = append(tags, tag)
+		}
+		return tags, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+func GetTagBooks(tag string) ([]BookLine, error) {
+	if DatabaseObj.Connected {
+		return queryBookLines("SELECT B.ID,B.TITLE FROM BOOKS B, BOOKS_TAGS T WHERE T.BOOK_ID=B.ID AND T.TAG=? ORDER BY B.TITLE", tag)
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+func GetBookTags(bookId int) ([]string, error) {
+	if DatabaseObj.Connected {
+		rows, err := DatabaseObj.DbObj.Query("SELECT TAG FROM BOOKS_TAGS WHERE BOOK_ID=? ORDER BY TAG", bookId)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var tags []string
+		for rows.Next() {
+			var tag string
+			err = rows.Scan(&tag)
+			if err != nil {
+				return nil, err
+			}
+			tags = append(tags, tag)
+		}
+		return tags, rows.Err()
+	}
+	return nil, errors.New("Not connected to DB")
+}
+
+// replaces tags of book
+func SetBookTags(bookId int, tags []string) error {
+	return WithTransaction(func(tx *sql.Tx) error {
+		return setBookTags(tx, bookId, tags)
+	})
+}
+
+func setBookTags(tx *sql.Tx, bookId int, tags []string) error {
+	_, err := tx.Exec("DELETE FROM BOOKS_TAGS WHERE BOOK_ID=?", bookId)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		_, err = tx.Exec("INSERT INTO BOOKS_TAGS (BOOK_ID,TAG) VALUES (?,?)", bookId, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func DbClose() error {
 	if DatabaseObj.Connected {
 		err := DatabaseObj.DbObj.Close()
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// bracketed parts of titles
+var bracketRegexp = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+
+// separators of authors
+var authorsRegexp = regexp.MustCompile(`(?i),|;|&| and `)
+
+// lower case title without punctuation, bracketed parts ("(2nd edition)") or leading article
+func NormalizeTitle(title string) string {
+	title = bracketRegexp.ReplaceAllString(strings.ToLower(title), " ")
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 1 {
+		switch words[0] {
+		case "the", "a", "an", "le", "la", "les", "l":
+			words = words[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// sorted lower case last names of authors: "Brian W. Kernighan, Alan Donovan" -> "donovan,kernighan"
+func NormalizeAuthors(authors string) string {
+	var names []string
+	for _, author := range authorsRegexp.Split(authors, -1) {
+		words := strings.FieldsFunc(strings.ToLower(author), func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+		if len(words) > 0 {
+			names = append(names, words[len(words)-1])
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// books are duplicates if they have same ISBN, share a downloaded file (same hash) or
+// have same normalized title with same authors and year, empty authors or year matching any.
+// A title match joins clusters only if all their books are SameBook: a book without year
+// is not a link between books of different years.
+// hashes are file hashes by book id. Returns clusters of 2 books or more ordered by id
+func FindDuplicates(books []*Book, hashes map[int][]string) [][]*Book {
+
+	// union find of book indexes, members of each root
+	parent := make([]int, len(books))
+	members := make([][]int, len(books))
+	for i := range parent {
+		parent[i] = i
+		members[i] = []int{i}
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		i, j = find(i), find(j)
+		if i != j {
+			parent[j] = i
+			members[i] = append(members[i], members[j]...)
+			members[j] = nil
+		}
+	}
+	// no book of a cluster conflicts with a book of the other one
+	compatible := func(i, j int) bool {
+		for _, a := range members[find(i)] {
+			for _, b := range members[find(j)] {
+				if !SameBook(books[a], books[b]) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	by_key := make(map[string]int)
+	link := func(key string, i int) {
+		if j, ok := by_key[key]; ok {
+			union(j, i)
+		} else {
+			by_key[key] = i
+		}
+	}
+
+	by_title := make(map[string][]int)
+	for i, book := range books {
+		if isbn, err := NormalizeIsbn(book.Isbn); err == nil {
+			link("isbn:"+isbn, i)
+		}
+		for _, hash := range hashes[book.Id] {
+			link("hash:"+hash, i)
+		}
+		title := NormalizeTitle(book.Title)
+		if len(title) > 0 {
+			by_title[title] = append(by_title[title], i)
+		}
+	}
+
+	for _, indexes := range by_title {
+		for a := 0; a < len(indexes); a += 1 {
+			for b := a + 1; b < len(indexes); b += 1 {
+				if compatible(indexes[a], indexes[b]) {
+					union(indexes[a], indexes[b])
+				}
+			}
+		}
+	}
+
+	groups := make(map[int][]*Book)
+	for i, book := range books {
+		root := find(i)
+		groups[root] = append(groups[root], book)
+	}
+
+	var clusters [][]*Book
+	for _, group := range groups {
+		if len(group) > 1 {
+			sort.Slice(group, func(i, j int) bool { return group[i].Id < group[j].Id })
+			clusters = append(clusters, group)
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i][0].Id < clusters[j][0].Id })
+	return clusters
+}
+
+// authors and year of books with same title match
+func SameBook(b1, b2 *Book) bool {
+	authors1, authors2 := NormalizeAuthors(b1.Authors), NormalizeAuthors(b2.Authors)
+	if len(authors1) > 0 && len(authors2) > 0 && authors1 != authors2 {
+		return false
+	}
+	if b1.Year > 0 && b2.Year > 0 && b1.Year != b2.Year {
+		return false
+	}
+	return true
+}
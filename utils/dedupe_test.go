@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/bookstore-go/utils"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	titles := map[string]string{
+		"The Go Programming Language":           "go programming language",
+		"Go programming language (2nd Edition)": "go programming language",
+		"L'art de la programmation":             "art de la programmation",
+		"  Clean   Code: A Handbook ":           "clean code a handbook",
+	}
+	for title, want := range titles {
+		if got := utils.NormalizeTitle(title); got != want {
+			t.Fatalf("Title mismatch for '%s' (want '%s', got '%s')\n", title, want, got)
+		}
+	}
+}
+
+func TestNormalizeAuthors(t *testing.T) {
+	got := utils.NormalizeAuthors("Brian W. Kernighan and Alan A. A. Donovan")
+	if want := utils.NormalizeAuthors("Alan Donovan, Brian Kernighan"); got != want || got != "donovan,kernighan" {
+		t.Fatalf("Authors mismatch (want '%s', got '%s')\n", want, got)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	books := []*utils.Book{
+		{Id: 1, Title: "The Go Programming Language", Authors: "Alan A. A. Donovan, Brian W. Kernighan", Year: 2015},
+		{Id: 2, Title: "Go Programming Language", Authors: "Kernighan, Donovan"},
+		{Id: 3, Title: "Go Programming Language", Authors: "Alan Donovan", Year: 2015},
+		{Id: 4, Title: "Learning Go", Isbn: "978-0-13-419044-0"},
+		{Id: 5, Title: "Programming in Go", Isbn: "9780134190440"},
+		{Id: 6, Title: "Concurrency in Go"},
+		{Id: 7, Title: "Go in Action"},
+		{Id: 8, Title: "Go in action", Year: 2016},
+		{Id: 9, Title: "Go in action", Year: 2017},
+	}
+	hashes := map[int][]string{6: {"abc"}, 10: {"abc"}}
+	books = append(books, &utils.Book{Id: 10, Title: "Concurrency with Go"})
+
+	clusters := utils.FindDuplicates(books, hashes)
+
+	// 7 without year matches 8 and 9, but 8 and 9 have different years
+	want := [][]int{{1, 2}, {4, 5}, {6, 10}, {7, 8}}
+	if len(clusters) != len(want) {
+		t.Fatalf("%d clusters expected (got %d)\n", len(want), len(clusters))
+	}
+	for i, cluster := range clusters {
+		if len(cluster) != len(want[i]) {
+			t.Fatalf("Cluster %d mismatch (want %v, got %d books)\n", i, want[i], len(cluster))
+		}
+		for j, book := range cluster {
+			if book.Id != want[i][j] {
+				t.Fatalf("Cluster %d mismatch (want %v, got book %d at %d)\n", i, want[i], book.Id, j)
+			}
+		}
+	}
+}
@@ -6,10 +6,7 @@ func Create_folder(folder string) (err error) {
 	_, err = os.Stat(folder)
 
 	if os.IsNotExist(err) {
-		errDir := os.MkdirAll(folder, 0755)
-		if errDir != nil {
-			panic(err)
-		}
+		err = os.MkdirAll(folder, 0755)
 	}
 	return
 }
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// first node of tree (depth first) matching predicate
+func FindNode(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := FindNode(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// all nodes of tree matching predicate; children of matching nodes are searched too
+func FindNodes(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var nodes []*html.Node
+	if match(n) {
+		nodes = append(nodes, n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, FindNodes(c, match)...)
+	}
+	return nodes
+}
+
+// predicate matching element by tag name and class; empty tag or class matches any
+func Element(tag, class string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && (len(tag) == 0 || n.Data == tag) && (len(class) == 0 || HasClass(n, class))
+	}
+}
+
+func Attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func HasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(Attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// text content of node with spaces collapsed
+func NodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// html source of node children
+func InnerHtml(n *html.Node) string {
+	var buf bytes.Buffer
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&buf, c)
+	}
+	return strings.TrimSpace(buf.String())
+}
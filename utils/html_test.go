@@ -0,0 +1,52 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/utils"
+	"golang.org/x/net/html"
+)
+
+func TestHtmlNodes(t *testing.T) {
+	src := `<div class="book main"><span class='title'>  Go   in
+	Action </span><ul><li>one</li><li class="tag">two</li><li class="tag">three</li></ul><a href="x.html">link</a></div>`
+
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+
+	div := utils.FindNode(doc, utils.Element("div", "main"))
+	if div == nil {
+		t.Fatal("div.main should be found\n")
+	}
+	if !utils.HasClass(div, "book") {
+		t.Fatal("div should have class book\n")
+	}
+
+	title := utils.FindNode(div, utils.Element("", "title"))
+	if got := utils.NodeText(title); got != "Go in Action" {
+		t.Fatalf("Text mismatch (want 'Go in Action', got '%s')\n", got)
+	}
+
+	tags := utils.FindNodes(div, utils.Element("li", "tag"))
+	if len(tags) != 2 || utils.NodeText(tags[1]) != "three" {
+		t.Fatalf("2 tags expected (got %d)\n", len(tags))
+	}
+
+	link := utils.FindNode(div, utils.Element("a", ""))
+	if utils.Attr(link, "href") != "x.html" {
+		t.Fatalf("href mismatch (got '%s')\n", utils.Attr(link, "href"))
+	}
+
+	ul := utils.FindNode(div, utils.Element("ul", ""))
+	want := `<li>one</li><li class="tag">two</li><li class="tag">three</li>`
+	if got := utils.InnerHtml(ul); got != want {
+		t.Fatalf("Inner html mismatch (want '%s', got '%s')\n", want, got)
+	}
+
+	if utils.FindNode(doc, utils.Element("table", "")) != nil {
+		t.Fatal("table should not be found\n")
+	}
+}
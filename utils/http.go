@@ -2,7 +2,9 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -13,14 +15,19 @@ import (
 type IHttpResponse interface {
 	Headers() http.Header
 	Content() []byte
+	Body() io.ReadCloser
 	StatusCode() int
 }
 type IHttpRequest interface {
 	Get() IHttpResponse
+	GetStream() IHttpResponse
 	Head() IHttpResponse
+	Post(content_type string, body io.Reader) IHttpResponse
+	Put(content_type string, body io.Reader) IHttpResponse
 	Error() error
 	Url() string
 	AddHeaders(map[string]string)
+	SetContext(ctx context.Context)
 }
 type IHttpRequestFactory interface {
 	CreateRequest(url_str string) IHttpRequest
@@ -30,12 +37,27 @@ type HttpResponse struct {
 	Content_    []byte
 	Headers_    http.Header
 	StatusCode_ int
+	Body_       io.ReadCloser
 }
 
+// content of streamed response is read (and body closed) on first call
 func (resp *HttpResponse) Content() []byte {
+	if resp.Content_ == nil && resp.Body_ != nil {
+		resp.Content_, _ = ioutil.ReadAll(resp.Body_)
+		resp.Body_.Close()
+		resp.Body_ = nil
+	}
 	return resp.Content_
 }
 
+// body of response, to be closed by caller
+func (resp *HttpResponse) Body() io.ReadCloser {
+	if resp.Body_ == nil {
+		return ioutil.NopCloser(bytes.NewReader(resp.Content_))
+	}
+	return resp.Body_
+}
+
 func (resp *HttpResponse) Headers() http.Header {
 	return resp.Headers_
 }
@@ -48,34 +70,85 @@ type HttpRequest struct {
 	Url_     string
 	Headers_ map[string]string
 	Error_   error
+	Context_ context.Context
+	Client_  *HttpClient // shared client if nil
 }
 
-func (req *HttpRequest) Get() IHttpResponse {
-	res, err := http.Get(req.Url_)
-	var response HttpResponse
-	if err == nil {
+func (req *HttpRequest) do(method string, body io.Reader) (*http.Response, error) {
+	ctx := req.Context_
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	http_req, err := http.NewRequestWithContext(ctx, method, req.Url_, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.Headers_ {
+		http_req.Header.Set(k, v)
+	}
+	client := req.Client_
+	if client == nil {
+		client = GetHttpClient()
+	}
+	return client.Do(http_req)
+}
+
+// response of request; body is read unless stream is true
+func (req *HttpRequest) response(res *http.Response, err error, stream bool) IHttpResponse {
+	if err != nil {
+		req.Error_ = err
+		return nil
+	}
+	response := &HttpResponse{Headers_: res.Header, StatusCode_: res.StatusCode, Body_: res.Body}
+	if !stream {
 		response.Content_, err = ioutil.ReadAll(res.Body)
-		response.Headers_ = res.Header
-		response.StatusCode_ = res.StatusCode
 		res.Body.Close()
-		return &response
+		response.Body_ = nil
+		if err != nil {
+			req.Error_ = err
+			return nil
+		}
 	}
-	req.Error_ = err
-	return nil
+	return response
+}
+
+func (req *HttpRequest) Get() IHttpResponse {
+	res, err := req.do("GET", nil)
+	return req.response(res, err, false)
+}
+
+// GET request without reading body: caller reads and closes response Body()
+func (req *HttpRequest) GetStream() IHttpResponse {
+	res, err := req.do("GET", nil)
+	return req.response(res, err, true)
 }
 
 func (req *HttpRequest) Head() IHttpResponse {
-	var response HttpResponse
-	res, err := http.Head(req.Url_)
+	res, err := req.do("HEAD", nil)
 	if err == nil {
-		response.Headers_ = res.Header
-		response.StatusCode_ = res.StatusCode
-		return &response
+		res.Body.Close()
+		return &HttpResponse{Headers_: res.Header, StatusCode_: res.StatusCode}
 	}
 	req.Error_ = err
 	return nil
 }
 
+func (req *HttpRequest) Post(content_type string, body io.Reader) IHttpResponse {
+	req.AddHeaders(map[string]string{"Content-Type": content_type})
+	res, err := req.do("POST", body)
+	return req.response(res, err, false)
+}
+
+func (req *HttpRequest) Put(content_type string, body io.Reader) IHttpResponse {
+	req.AddHeaders(map[string]string{"Content-Type": content_type})
+	res, err := req.do("PUT", body)
+	return req.response(res, err, false)
+}
+
+func (req *HttpRequest) SetContext(ctx context.Context) {
+	req.Context_ = ctx
+}
+
 func (req *HttpRequest) Error() error {
 	return req.Error_
 }
@@ -85,17 +158,22 @@ func (req *HttpRequest) Url() string {
 }
 
 func (req *HttpRequest) AddHeaders(headers map[string]string) {
+	if req.Headers_ == nil {
+		req.Headers_ = make(map[string]string)
+	}
 	for k, v := range headers {
 		req.Headers_[k] = v
 	}
 }
 
 type HttpRequestFactoryImpl struct {
+	Client *HttpClient
 }
 
 func (f *HttpRequestFactoryImpl) CreateRequest(url_str string) IHttpRequest {
 	var req HttpRequest
 	req.Url_ = url_str
+	req.Client_ = f.Client
 	return &req
 }
 
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// [Http] section of configuration; zero values use defaults
+type HttpConfig struct {
+	Timeout       int    // connect and response header timeout, seconds
+	Proxy         string // proxy url, environment HTTP_PROXY/HTTPS_PROXY if not set
+	UserAgent     string `toml:"user-agent"`
+	Headers       map[string]string
+	MaxRetries    int    `toml:"max-retries"`     // retries of idempotent requests on 429/5xx, -1 disables retries
+	RetryDelay    int    `toml:"retry-delay"`     // first retry delay, milliseconds; doubled on each retry
+	MaxRetryDelay int    `toml:"max-retry-delay"` // seconds
+	Record        string // directory where request/response pairs are recorded as test fixtures
+}
+
+type HttpClient struct {
+	Client *http.Client
+	Config HttpConfig
+}
+
+const (
+	DefaultTimeout       = 30
+	DefaultUserAgent     = "bookstore-go"
+	DefaultMaxRetries    = 3
+	DefaultRetryDelay    = 500
+	DefaultMaxRetryDelay = 60
+)
+
+func NewHttpClient(conf HttpConfig) (*HttpClient, error) {
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultTimeout
+	}
+	if len(conf.UserAgent) == 0 {
+		conf.UserAgent = DefaultUserAgent
+	}
+	if conf.MaxRetries == 0 {
+		conf.MaxRetries = DefaultMaxRetries
+	} else if conf.MaxRetries < 0 {
+		conf.MaxRetries = 0
+	}
+	if conf.RetryDelay <= 0 {
+		conf.RetryDelay = DefaultRetryDelay
+	}
+	if conf.MaxRetryDelay <= 0 {
+		conf.MaxRetryDelay = DefaultMaxRetryDelay
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if len(conf.Proxy) > 0 {
+		proxy_url, err := url.Parse(conf.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(proxy_url)
+	}
+
+	timeout := time.Duration(conf.Timeout) * time.Second
+
+	// no global client timeout: it would include reading body of large book files
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          10,
+	}
+
+	if len(conf.Record) > 0 {
+		return &HttpClient{&http.Client{Transport: &RecordTransport{conf.Record, transport}}, conf}, nil
+	}
+	return &HttpClient{&http.Client{Transport: transport}, conf}, nil
+}
+
+// sends request with configured headers; retries idempotent requests on 429 and 5xx status
+func (c *HttpClient) Do(req *http.Request) (*http.Response, error) {
+
+	if len(req.Header.Get("User-Agent")) == 0 {
+		req.Header.Set("User-Agent", c.Config.UserAgent)
+	}
+	for k, v := range c.Config.Headers {
+		if len(req.Header.Get(k)) == 0 {
+			req.Header.Set(k, v)
+		}
+	}
+
+	for attempt := 0; ; attempt += 1 {
+
+		resp, err := c.Client.Do(req)
+
+		if err != nil || !IsRetryStatus(resp.StatusCode) || attempt >= c.Config.MaxRetries || !IsIdempotent(req) {
+			return resp, err
+		}
+		// body already sent cannot be sent again
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := c.RetryDelay(attempt, resp)
+		resp.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (c *HttpClient) Get(url_str string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url_str, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *HttpClient) Head(url_str string) (*http.Response, error) {
+	req, err := http.NewRequest("HEAD", url_str, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *HttpClient) PostForm(url_str string, values url.Values) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url_str, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
+
+func IsRetryStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// request can be sent twice: POST is not, unless it has an idempotency key
+// (an authorization code exchange fails the second time)
+func IsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return len(req.Header.Get("Idempotency-Key")) > 0 || len(req.Header.Get("X-Idempotency-Key")) > 0
+}
+
+// delay before retry: Retry-After header if any, else exponential backoff; not more than MaxRetryDelay
+func (c *HttpClient) RetryDelay(attempt int, resp *http.Response) time.Duration {
+
+	max_delay := time.Duration(c.Config.MaxRetryDelay) * time.Second
+
+	if resp != nil {
+		retry_after := resp.Header.Get("Retry-After")
+		if len(retry_after) > 0 {
+			if sec, err := strconv.Atoi(retry_after); err == nil && sec >= 0 {
+				if sec > c.Config.MaxRetryDelay {
+					return max_delay
+				}
+				return time.Duration(sec) * time.Second
+			}
+			if date, err := http.ParseTime(retry_after); err == nil {
+				delay := time.Until(date)
+				if delay < 0 {
+					delay = 0
+				} else if delay > max_delay {
+					delay = max_delay
+				}
+				return delay
+			}
+		}
+	}
+
+	delay := time.Duration(c.Config.RetryDelay) * time.Millisecond
+	for i := 0; i < attempt && delay < max_delay; i += 1 {
+		delay *= 2
+	}
+	if delay > max_delay {
+		delay = max_delay
+	}
+	return delay
+}
+
+//------------------------------------------------------------------
+//						global variables
+//------------------------------------------------------------------
+var http_client, _ = NewHttpClient(HttpConfig{})
+
+func GetHttpClient() *HttpClient {
+	return http_client
+}
+
+func ConfigureHttpClient(conf HttpConfig) error {
+	client, err := NewHttpClient(conf)
+	if err != nil {
+		return errors.New("Invalid http configuration: " + err.Error())
+	}
+	http_client = client
+	return nil
+}
@@ -0,0 +1,191 @@
+package utils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bookstore-go/utils"
+)
+
+func TestHttpClientRetry(t *testing.T) {
+	var nb_calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		nb_calls += 1
+		if nb_calls < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client, err := utils.NewHttpClient(utils.HttpConfig{RetryDelay: 1})
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected status code %d\n", resp.StatusCode)
+	}
+	if nb_calls != 3 {
+		t.Fatalf("Server should be called 3 times (got %d)\n", nb_calls)
+	}
+}
+
+func TestHttpClientNoRetry(t *testing.T) {
+	var nb_calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		nb_calls += 1
+		rw.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, _ := utils.NewHttpClient(utils.HttpConfig{MaxRetries: -1})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Unexpected status code %d\n", resp.StatusCode)
+	}
+	if nb_calls != 1 {
+		t.Fatalf("Server should be called once (got %d)\n", nb_calls)
+	}
+}
+
+func TestHttpClientPostNoRetry(t *testing.T) {
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		bodies = append(bodies, req.PostForm.Get("code"))
+		if len(bodies) == 1 {
+			rw.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := utils.NewHttpClient(utils.HttpConfig{RetryDelay: 1})
+
+	// authorization code can be used once
+	resp, err := client.PostForm(server.URL, url.Values{"code": {"1234"}})
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || len(bodies) != 1 {
+		t.Fatalf("POST should not be retried (got status %d, %d calls)\n", resp.StatusCode, len(bodies))
+	}
+
+	// idempotency key allows retry, body is sent again
+	bodies = nil
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader(url.Values{"code": {"1234"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", "key")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 {
+		t.Fatalf("Server should be called twice (got %d)\n", len(bodies))
+	}
+	for i, b := range bodies {
+		if b != "1234" {
+			t.Fatalf("Form value %d mismatch (want '1234', got '%s')\n", i, b)
+		}
+	}
+}
+
+func TestHttpClientHeaders(t *testing.T) {
+	var got http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		got = req.Header
+	}))
+	defer server.Close()
+
+	client, _ := utils.NewHttpClient(utils.HttpConfig{UserAgent: "test-agent", Headers: map[string]string{"X-Test": "conf"}})
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("X-Request", "req")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	resp.Body.Close()
+
+	want := map[string]string{"User-Agent": "test-agent", "X-Test": "conf", "X-Request": "req"}
+	for k, v := range want {
+		if got.Get(k) != v {
+			t.Fatalf("Header[%s] mismatch (want '%s', got '%s')\n", k, v, got.Get(k))
+		}
+	}
+}
+
+func TestHttpClientRetryDelay(t *testing.T) {
+	client, _ := utils.NewHttpClient(utils.HttpConfig{RetryDelay: 100, MaxRetryDelay: 1})
+
+	tests := []struct {
+		attempt     int
+		retry_after string
+		want        time.Duration
+	}{
+		{0, "", 100 * time.Millisecond},
+		{1, "", 200 * time.Millisecond},
+		{2, "", 400 * time.Millisecond},
+		{5, "", time.Second},
+		{0, "1", time.Second},
+		{0, "3", time.Second},
+		{0, "86400", time.Second},
+		{0, "Mon, 02 Jan 2006 15:04:05 GMT", 0},
+		{0, time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), time.Second},
+	}
+
+	for _, test := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if len(test.retry_after) > 0 {
+			resp.Header.Set("Retry-After", test.retry_after)
+		}
+		got := client.RetryDelay(test.attempt, resp)
+		if got != test.want {
+			t.Fatalf("Retry delay (attempt=%d, Retry-After='%s') mismatch (want %v, got %v)\n", test.attempt, test.retry_after, test.want, got)
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	var got string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	req := utils.CreateRequest(server.URL)
+	req.AddHeaders(map[string]string{"Authorization": "Bearer token"})
+
+	response := req.Get()
+	if response == nil {
+		t.Fatalf("response should not be null (%s)\n", req.Error())
+	}
+	if got != "Bearer token" {
+		t.Fatalf("Authorization header mismatch (want 'Bearer token', got '%s')\n", got)
+	}
+}
@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// request/response pair saved by RecordTransport and served by ReplayTransport
+type HttpFixture struct {
+	Method         string
+	Url            string
+	RequestHeaders http.Header `json:",omitempty"`
+	RequestBody    string      `json:",omitempty"` // as returned by FixtureBody
+	StatusCode     int
+	Headers        http.Header
+	Body           string
+	Base64         bool `json:",omitempty"` // binary body encoded in base64
+}
+
+// headers with credentials are not written in fixtures
+var redacted_headers = []string{"Authorization", "Cookie", "Set-Cookie", "Boxapi"}
+
+// form values and JSON fields with credentials are replaced in request and response bodies of fixtures
+var redacted_values = []string{"client_secret", "refresh_token", "access_token", "id_token", "code", "assertion", "password"}
+
+const Redacted = "REDACTED"
+
+// fixture key: method and url with sorted query arguments, hash of request body if any
+func FixtureKey(method string, u *url.URL, body string) string {
+	key_url := *u
+	key_url.RawQuery = u.Query().Encode()
+	key_url.Fragment = ""
+	key := strings.ToUpper(method) + " " + key_url.String()
+	if len(body) > 0 {
+		sum := sha1.Sum([]byte(body))
+		key += " body:" + hex.EncodeToString(sum[:6])
+	}
+	return key
+}
+
+// request body as written in fixtures: form values and JSON fields are sorted and credentials redacted
+func FixtureBody(content_type string, body []byte) string {
+	return string(RedactBody(content_type, body))
+}
+
+// credentials of form or JSON body replaced with Redacted; other bodies are returned as is
+func RedactBody(content_type string, body []byte) []byte {
+	if strings.HasPrefix(content_type, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err == nil {
+			for _, k := range redacted_values {
+				if _, ok := values[k]; ok {
+					values.Set(k, Redacted)
+				}
+			}
+			return []byte(values.Encode())
+		}
+	}
+	if strings.Contains(content_type, "json") {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var v interface{}
+		if decoder.Decode(&v) == nil {
+			var out bytes.Buffer
+			encoder := json.NewEncoder(&out)
+			encoder.SetEscapeHTML(false)
+			if encoder.Encode(redactJson(v)) == nil {
+				return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+			}
+		}
+	}
+	return body
+}
+
+// string values of credential fields are replaced in objects, at any depth
+func redactJson(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, field := range value {
+			if _, ok := field.(string); ok && isRedacted(k) {
+				value[k] = Redacted
+			} else {
+				value[k] = redactJson(field)
+			}
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = redactJson(value[i])
+		}
+	}
+	return v
+}
+
+func isRedacted(name string) bool {
+	for _, k := range redacted_values {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reads body of a cloned request; body is replaced to be sent again
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	content, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(content))
+	return content, nil
+}
+
+func NewHttpFixture(req *http.Request, body []byte, resp *http.Response, content []byte) *HttpFixture {
+	fixture := &HttpFixture{Method: req.Method, Url: req.URL.String(), StatusCode: resp.StatusCode}
+	fixture.RequestBody = FixtureBody(req.Header.Get("Content-Type"), body)
+
+	fixture.RequestHeaders = req.Header.Clone()
+	fixture.Headers = resp.Header.Clone()
+	for _, h := range redacted_headers {
+		fixture.RequestHeaders.Del(h)
+		fixture.Headers.Del(h)
+	}
+
+	// tokens of responses are not written either
+	if redacted := RedactBody(resp.Header.Get("Content-Type"), content); !bytes.Equal(redacted, content) {
+		content = redacted
+		fixture.Headers.Del("Content-Length")
+	}
+	if utf8.Valid(content) {
+		fixture.Body = string(content)
+	} else {
+		fixture.Body = base64.StdEncoding.EncodeToString(content)
+		fixture.Base64 = true
+	}
+	return fixture
+}
+
+func (fixture *HttpFixture) Content() ([]byte, error) {
+	if fixture.Base64 {
+		return base64.StdEncoding.DecodeString(fixture.Body)
+	}
+	return []byte(fixture.Body), nil
+}
+
+func (fixture *HttpFixture) Response(req *http.Request) (*http.Response, error) {
+	content, err := fixture.Content()
+	if err != nil {
+		return nil, err
+	}
+	headers := fixture.Headers
+	if headers == nil {
+		headers = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.StatusCode, http.StatusText(fixture.StatusCode)),
+		StatusCode:    fixture.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          ioutil.NopCloser(bytes.NewReader(content)),
+		ContentLength: int64(len(content)),
+		Request:       req,
+	}, nil
+}
+
+// writes fixture in dir; file name is derived from fixture key
+func SaveHttpFixture(dir string, fixture *HttpFixture) error {
+	u, err := url.Parse(fixture.Url)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	sum := sha1.Sum([]byte(FixtureKey(fixture.Method, u, fixture.RequestBody)))
+	file_name := strings.ToLower(fixture.Method) + "_" + u.Hostname() + "_" + hex.EncodeToString(sum[:6]) + ".json"
+
+	content, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(dir, file_name), content, 0644)
+}
+
+// fixtures of dir (*.json files) by fixture key
+func LoadHttpFixtures(dir string) (map[string]*HttpFixture, error) {
+	files, err := filepath.Glob(path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	fixtures := make(map[string]*HttpFixture)
+
+	for _, f := range files {
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		fixture := &HttpFixture{}
+		err = json.Unmarshal(content, fixture)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid fixture %s: %v", f, err)
+		}
+		if len(fixture.Method) == 0 {
+			fixture.Method = "GET"
+		}
+		u, err := url.Parse(fixture.Url)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid fixture %s url: %v", f, err)
+		}
+		fixtures[FixtureKey(fixture.Method, u, fixture.RequestBody)] = fixture
+	}
+	return fixtures, nil
+}
+
+// http.RoundTripper saving every request/response pair in Dir.
+// Response body is read in memory to be recorded.
+type RecordTransport struct {
+	Dir       string
+	Transport http.RoundTripper
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	// body is sent and recorded
+	req = req.Clone(req.Context())
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(content))
+
+	err = SaveHttpFixture(t.Dir, NewHttpFixture(req, body, resp, content))
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// http.RoundTripper serving responses from fixtures of Dir; no network access
+type ReplayTransport struct {
+	Dir      string
+	fixtures map[string]*HttpFixture
+	once     sync.Once
+	err      error
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(func() {
+		t.fixtures, t.err = LoadHttpFixtures(t.Dir)
+	})
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	req = req.Clone(req.Context())
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := FixtureKey(req.Method, req.URL, FixtureBody(req.Header.Get("Content-Type"), body))
+	fixture, ok := t.fixtures[key]
+	if !ok {
+		return nil, fmt.Errorf("No fixture in %s for %s", t.Dir, key)
+	}
+	return fixture.Response(req)
+}
+
+// request factory replaying fixtures of dir; to be used with SetHttpRequestFactory in tests
+func NewReplayRequestFactory(dir string) IHttpRequestFactory {
+	client, _ := NewHttpClient(HttpConfig{MaxRetries: -1})
+	client.Client.Transport = &ReplayTransport{Dir: dir}
+	return &HttpRequestFactoryImpl{client}
+}
@@ -0,0 +1,216 @@
+package utils_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bookstore-go/utils"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	binary := string([]byte{0x89, 'P', 'N', 'G', 0xff, 0x00})
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/img.png" {
+			rw.Header().Set("Content-Type", "image/png")
+			io.WriteString(rw, binary)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html")
+		io.WriteString(rw, "<html>page "+req.URL.Query().Get("id")+"</html>")
+	}))
+
+	client, err := utils.NewHttpClient(utils.HttpConfig{Record: dir})
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	record_factory := &utils.HttpRequestFactoryImpl{Client: client}
+
+	for _, u := range []string{server.URL + "/page?id=1&lang=en", server.URL + "/img.png"} {
+		req := record_factory.CreateRequest(u)
+		req.AddHeaders(map[string]string{"Authorization": "Bearer secret"})
+		if req.Get() == nil {
+			t.Fatalf("Recording %s failed: %s\n", u, req.Error())
+		}
+	}
+	server.Close()
+
+	fixtures, err := utils.LoadHttpFixtures(dir)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if len(fixtures) != 2 {
+		t.Fatalf("2 fixtures should be recorded (got %d)\n", len(fixtures))
+	}
+	for k, f := range fixtures {
+		if len(f.RequestHeaders.Get("Authorization")) > 0 {
+			t.Fatalf("Authorization header should not be recorded in %s\n", k)
+		}
+	}
+
+	old_factory := utils.SetHttpRequestFactory(utils.NewReplayRequestFactory(dir))
+	defer utils.SetHttpRequestFactory(old_factory)
+
+	tests := []struct {
+		url          string
+		content      string
+		content_type string
+	}{
+		// query arguments order does not matter
+		{server.URL + "/page?lang=en&id=1", "<html>page 1</html>", "text/html"},
+		{server.URL + "/img.png", binary, "image/png"},
+	}
+	for _, test := range tests {
+		req := utils.CreateRequest(test.url)
+		response := req.Get()
+		if response == nil {
+			t.Fatalf("Replay of %s failed: %s\n", test.url, req.Error())
+		}
+		if string(response.Content()) != test.content {
+			t.Fatalf("Replayed content mismatch (want '%q', got '%q')\n", test.content, string(response.Content()))
+		}
+		if response.Headers().Get("Content-Type") != test.content_type {
+			t.Fatalf("Replayed Content-Type mismatch (want '%s', got '%s')\n", test.content_type, response.Headers().Get("Content-Type"))
+		}
+	}
+
+	req := utils.CreateRequest(server.URL + "/not-recorded")
+	if req.Get() != nil || req.Error() == nil {
+		t.Fatal("Request without fixture should fail\n")
+	}
+}
+
+func TestRecordReplayPost(t *testing.T) {
+	dir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		io.WriteString(rw, `{"grant":"`+req.PostForm.Get("grant_type")+`"}`)
+	}))
+
+	client, err := utils.NewHttpClient(utils.HttpConfig{Record: dir})
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	record_factory := &utils.HttpRequestFactoryImpl{Client: client}
+
+	forms := []string{"grant_type=refresh_token&refresh_token=secret", "grant_type=client_credentials&client_secret=secret"}
+	for _, form := range forms {
+		req := record_factory.CreateRequest(server.URL + "/token")
+		if req.Post("application/x-www-form-urlencoded", strings.NewReader(form)) == nil {
+			t.Fatalf("Recording %s failed: %s\n", form, req.Error())
+		}
+	}
+	server.Close()
+
+	fixtures, err := utils.LoadHttpFixtures(dir)
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if len(fixtures) != 2 {
+		t.Fatalf("2 fixtures should be recorded (got %d)\n", len(fixtures))
+	}
+	for k, f := range fixtures {
+		if strings.Contains(f.RequestBody, "=secret") {
+			t.Fatalf("Credentials should not be recorded in %s (got '%s')\n", k, f.RequestBody)
+		}
+	}
+
+	old_factory := utils.SetHttpRequestFactory(utils.NewReplayRequestFactory(dir))
+	defer utils.SetHttpRequestFactory(old_factory)
+
+	// same url, response chosen by body; credentials do not matter
+	tests := map[string]string{
+		"refresh_token=other&grant_type=refresh_token":       `{"grant":"refresh_token"}`,
+		"grant_type=client_credentials&client_secret=secret": `{"grant":"client_credentials"}`,
+	}
+	for form, want := range tests {
+		req := utils.CreateRequest(server.URL + "/token")
+		response := req.Post("application/x-www-form-urlencoded", strings.NewReader(form))
+		if response == nil {
+			t.Fatalf("Replay of %s failed: %s\n", form, req.Error())
+		}
+		if string(response.Content()) != want {
+			t.Fatalf("Replayed content mismatch for %s (want '%s', got '%s')\n", form, want, string(response.Content()))
+		}
+	}
+
+	req := utils.CreateRequest(server.URL + "/token")
+	if req.Post("application/x-www-form-urlencoded", strings.NewReader("grant_type=password")) != nil {
+		t.Fatal("Request with body without fixture should fail\n")
+	}
+}
+
+func TestRecordRedactTokens(t *testing.T) {
+	dir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/form" {
+			rw.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+			io.WriteString(rw, "access_token=live-access&token_type=Bearer&expires_in=3599")
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(rw, `{"access_token":"live-access","refresh_token":"live-refresh","expires_in":3600,"user":{"id_token":"live-id","name":"a&b"}}`)
+	}))
+
+	client, err := utils.NewHttpClient(utils.HttpConfig{Record: dir})
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	record_factory := &utils.HttpRequestFactoryImpl{Client: client}
+
+	req := record_factory.CreateRequest(server.URL + "/json")
+	response := req.Post("application/json", strings.NewReader(`{"grant_type":"refresh_token","refresh_token":"live-refresh","client_secret":"live-secret"}`))
+	if response == nil {
+		t.Fatalf("Recording failed: %s\n", req.Error())
+	}
+	// caller gets tokens, fixture does not
+	if !strings.Contains(string(response.Content()), "live-access") {
+		t.Fatalf("Recorded response should not be redacted (got '%s')\n", string(response.Content()))
+	}
+	req = record_factory.CreateRequest(server.URL + "/form")
+	if req.Get() == nil {
+		t.Fatalf("Recording failed: %s\n", req.Error())
+	}
+	server.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("2 fixtures should be recorded (got %d)\n", len(files))
+	}
+	for _, f := range files {
+		content, _ := ioutil.ReadFile(f)
+		if strings.Contains(string(content), "live-") {
+			t.Fatalf("Fixture %s should not contain secrets (got\n%s)\n", f, content)
+		}
+	}
+
+	old_factory := utils.SetHttpRequestFactory(utils.NewReplayRequestFactory(dir))
+	defer utils.SetHttpRequestFactory(old_factory)
+
+	tests := map[string]string{
+		"/json": `{"access_token":"REDACTED","expires_in":3600,"refresh_token":"REDACTED","user":{"id_token":"REDACTED","name":"a&b"}}`,
+		"/form": "access_token=REDACTED&expires_in=3599&token_type=Bearer",
+	}
+	for path, want := range tests {
+		req := utils.CreateRequest(server.URL + path)
+		if path == "/json" {
+			response = req.Post("application/json", strings.NewReader(`{"client_secret":"other","grant_type":"refresh_token","refresh_token":"other"}`))
+		} else {
+			response = req.Get()
+		}
+		if response == nil {
+			t.Fatalf("Replay of %s failed: %s\n", path, req.Error())
+		}
+		if string(response.Content()) != want {
+			t.Fatalf("Replayed content mismatch (want '%s', got '%s')\n", want, string(response.Content()))
+		}
+	}
+}
@@ -2,8 +2,12 @@ package utils_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -17,6 +21,8 @@ import (
 type HttpRequestTest struct {
 	NbGetCall          int
 	NbHeadCall         int
+	NbPostCall         int
+	PostContent        []byte
 	ResponseContent    []byte
 	ResponseStatusCode int
 	ResponseHeaders    http.Header
@@ -42,6 +48,27 @@ func (req *HttpRequestTest) Get() utils.IHttpResponse {
 	return &response
 }
 
+func (req *HttpRequestTest) GetStream() utils.IHttpResponse {
+	return req.Get()
+}
+
+func (req *HttpRequestTest) Post(content_type string, body io.Reader) utils.IHttpResponse {
+	req.PostContent, _ = ioutil.ReadAll(body)
+	req.NbPostCall += 1
+	var response utils.HttpResponse
+	response.Content_ = req.ResponseContent
+	response.Headers_ = req.ResponseHeaders
+	response.StatusCode_ = req.ResponseStatusCode
+	return &response
+}
+
+func (req *HttpRequestTest) Put(content_type string, body io.Reader) utils.IHttpResponse {
+	return req.Post(content_type, body)
+}
+
+func (req *HttpRequestTest) SetContext(ctx context.Context) {
+}
+
 func (req *HttpRequestTest) Head() utils.IHttpResponse {
 	var response utils.HttpResponse
 	response.Headers_ = req.ResponseHeaders
@@ -347,3 +374,102 @@ func TestGetImageUrl(t *testing.T) {
 		t.Fatalf("Url image mismatch (want'%s', got'%s'\n", want, got)
 	}
 }
+
+func TestGetStreamRequest(t *testing.T) {
+	want := strings.Repeat("book content ", 1000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		io.WriteString(rw, want)
+	}))
+	defer server.Close()
+
+	req := utils.CreateRequest(server.URL)
+	response := req.GetStream()
+
+	if response == nil {
+		t.Fatalf("response should not be null (%s)\n", req.Error())
+	}
+
+	body := response.Body()
+	got, err := ioutil.ReadAll(body)
+	body.Close()
+
+	if err != nil {
+		t.Fatalf("Error should be null (got %s)\n", err)
+	}
+	if string(got) != want {
+		t.Fatalf("Streamed content mismatch (want %d bytes, got %d bytes)\n", len(want), len(got))
+	}
+}
+
+func TestPostRequest(t *testing.T) {
+	var got_method, got_type, got_body string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		got_method = req.Method
+		got_type = req.Header.Get("Content-Type")
+		content, _ := ioutil.ReadAll(req.Body)
+		got_body = string(content)
+		io.WriteString(rw, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	req := utils.CreateRequest(server.URL)
+	response := req.Post("application/json", strings.NewReader(`{"id":1}`))
+
+	if response == nil {
+		t.Fatalf("response should not be null (%s)\n", req.Error())
+	}
+	if got_method != "POST" || got_type != "application/json" || got_body != `{"id":1}` {
+		t.Fatalf("Unexpected request received (method='%s', type='%s', body='%s')\n", got_method, got_type, got_body)
+	}
+	if string(response.Content()) != `{"ok":true}` {
+		t.Fatalf("Content mismatch (got '%s')\n", string(response.Content()))
+	}
+
+	req = utils.CreateRequest(server.URL)
+	response = req.Put("text/plain", strings.NewReader("put"))
+	if response == nil || got_method != "PUT" || got_body != "put" {
+		t.Fatalf("Unexpected PUT request received (method='%s', body='%s')\n", got_method, got_body)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := utils.CreateRequest(server.URL)
+	req.SetContext(ctx)
+
+	if response := req.Get(); response != nil {
+		t.Fatalf("response should be null (got %#v)\n", response)
+	}
+	if req.Error() == nil {
+		t.Fatal("Error should not be null\n")
+	}
+}
+
+func TestMockFactoryStream(t *testing.T) {
+	factory := &HttpRequestFactoryTest{}
+	factory.Req.ResponseContent = []byte("mock content")
+	factory.Req.ResponseStatusCode = 200
+
+	old_factory := utils.SetHttpRequestFactory(factory)
+	defer utils.SetHttpRequestFactory(old_factory)
+
+	response := utils.CreateRequest("http://mock.test/file").GetStream()
+	body := response.Body()
+	got, _ := ioutil.ReadAll(body)
+	body.Close()
+
+	if string(got) != "mock content" {
+		t.Fatalf("Streamed content mismatch (want 'mock content', got '%s')\n", string(got))
+	}
+	if factory.Req.NbGetCall != 1 {
+		t.Fatalf("Get should be called once (got %d)\n", factory.Req.NbGetCall)
+	}
+}
@@ -34,3 +34,54 @@ func Extract_bytes(pattern string, bytes_input []byte) ([]byte, error) {
 
 	return res, err
 }
+
+var ErrInvalidIsbn = errors.New("Invalid ISBN")
+
+// ISBN-10 or ISBN-13 without separators; check digit is verified
+func NormalizeIsbn(isbn string) (string, error) {
+	var digits []byte
+	for i := 0; i < len(isbn); i += 1 {
+		c := isbn[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case (c == 'X' || c == 'x') && len(digits) == 9:
+			digits = append(digits, 'X')
+		case c == '-' || c == ' ':
+		default:
+			return "", ErrInvalidIsbn
+		}
+	}
+
+	sum := 0
+	switch len(digits) {
+	case 10:
+		for i, c := range digits {
+			v := int(c - '0')
+			if c == 'X' {
+				v = 10
+			}
+			sum += (10 - i) * v
+		}
+		if sum%11 != 0 {
+			return "", ErrInvalidIsbn
+		}
+	case 13:
+		for i, c := range digits {
+			if c == 'X' {
+				return "", ErrInvalidIsbn
+			}
+			if i%2 == 0 {
+				sum += int(c - '0')
+			} else {
+				sum += 3 * int(c-'0')
+			}
+		}
+		if sum%10 != 0 {
+			return "", ErrInvalidIsbn
+		}
+	default:
+		return "", ErrInvalidIsbn
+	}
+	return string(digits), nil
+}
@@ -48,3 +48,23 @@ func TestExtractBytes(t *testing.T) {
 		t.Fatalf("Extracted string mismatch (want '%s', got '%s'\n", string(want), string(got))
 	}
 }
+
+func TestNormalizeIsbn(t *testing.T) {
+	valid := map[string]string{
+		"978-0-13-419044-0": "9780134190440",
+		"0-13-419044-0":     "0134190440",
+		"0 8044 2957 X":     "080442957X",
+	}
+	for isbn, want := range valid {
+		got, err := utils.NormalizeIsbn(isbn)
+		if err != nil || got != want {
+			t.Fatalf("ISBN mismatch for '%s' (want '%s', got '%s', %v)\n", isbn, want, got, err)
+		}
+	}
+
+	for _, isbn := range []string{"978-0-13-419044-1", "0134190441", "12345", "ISBN 0134190440"} {
+		if _, err := utils.NormalizeIsbn(isbn); err != utils.ErrInvalidIsbn {
+			t.Fatalf("'%s' should be invalid (got %v)\n", isbn, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// subject in subjects tree; NbBooks counts books of subject, TotalBooks distinct books of subject and descendants
+type SubjectNode struct {
+	Subject
+	Parent     *SubjectNode
+	Children   []*SubjectNode
+	TotalBooks uint
+}
+
+type Tag struct {
+	Name    string
+	NbBooks uint
+}
+
+// builds tree from subjects and book ids by subject id; subjects with unknown parent or in a parent cycle are roots
+func BuildSubjectTree(subjects []Subject, books map[uint][]int) []*SubjectNode {
+
+	nodes := make(map[uint]*SubjectNode)
+	for _, sub := range subjects {
+		node := &SubjectNode{Subject: sub}
+		node.NbBooks = uint(len(books[sub.Id]))
+		nodes[sub.Id] = node
+	}
+
+	var roots []*SubjectNode
+	for _, sub := range subjects {
+		node := nodes[sub.Id]
+		parent, ok := nodes[sub.ParentId]
+		if ok && !node.IsAncestorOf(parent) {
+			node.Parent = parent
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortSubjects(roots)
+	for _, node := range roots {
+		node.rollUp(books)
+	}
+	return roots
+}
+
+// true if node is other or one of its ancestors
+func (node *SubjectNode) IsAncestorOf(other *SubjectNode) bool {
+	for ; other != nil; other = other.Parent {
+		if other == node {
+			return true
+		}
+	}
+	return false
+}
+
+// ids of subject and its descendants
+func (node *SubjectNode) SubtreeIds() []uint {
+	ids := []uint{node.Id}
+	for _, child := range node.Children {
+		ids = append(ids, child.SubtreeIds()...)
+	}
+	return ids
+}
+
+// "Programming > Languages > Go"
+func (node *SubjectNode) Path() string {
+	path := node.Name
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		path = parent.Name + " > " + path
+	}
+	return path
+}
+
+// sets TotalBooks of subtree, returns set of books of subtree
+func (node *SubjectNode) rollUp(books map[uint][]int) map[int]bool {
+	set := make(map[int]bool)
+	for _, id := range books[node.Id] {
+		set[id] = true
+	}
+	sortSubjects(node.Children)
+	for _, child := range node.Children {
+		for id := range child.rollUp(books) {
+			set[id] = true
+		}
+	}
+	node.TotalBooks = uint(len(set))
+	return set
+}
+
+func sortSubjects(nodes []*SubjectNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+	})
+}
+
+// lower case tags of comma separated list, without duplicates
+func NormalizeTags(tags string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+		if len(tag) > 0 && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
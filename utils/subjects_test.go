@@ -0,0 +1,63 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/bookstore-go/utils"
+)
+
+func TestBuildSubjectTree(t *testing.T) {
+	subjects := []utils.Subject{
+		{Id: 1, Name: "Programming"},
+		{Id: 2, Name: "Languages", ParentId: 1},
+		{Id: 3, Name: "Go", ParentId: 2},
+		{Id: 4, Name: "Algorithms", ParentId: 1},
+		{Id: 5, Name: "Databases"},
+		// cycle: 6 and 7 are each other's parent
+		{Id: 6, Name: "Cycle A", ParentId: 7},
+		{Id: 7, Name: "Cycle B", ParentId: 6},
+		{Id: 8, Name: "Orphan", ParentId: 99},
+	}
+	books := map[uint][]int{1: {10}, 2: {11}, 3: {11, 12}, 4: {10, 13}, 5: {14}}
+
+	roots := utils.BuildSubjectTree(subjects, books)
+
+	names := []string{}
+	for _, root := range roots {
+		names = append(names, root.Name)
+	}
+	if len(roots) != 4 || names[0] != "Cycle B" || names[1] != "Databases" || names[2] != "Orphan" || names[3] != "Programming" {
+		t.Fatalf("Roots mismatch (got %q)\n", names)
+	}
+
+	prog := roots[3]
+	if len(prog.Children) != 2 || prog.Children[0].Name != "Algorithms" || prog.Children[1].Name != "Languages" {
+		t.Fatalf("Children of Programming mismatch (got %d)\n", len(prog.Children))
+	}
+	// books 10, 11, 12, 13 counted once
+	if prog.NbBooks != 1 || prog.TotalBooks != 4 {
+		t.Fatalf("Book counts mismatch (want 1/4, got %d/%d)\n", prog.NbBooks, prog.TotalBooks)
+	}
+	langs := prog.Children[1]
+	if langs.TotalBooks != 2 {
+		t.Fatalf("Languages should have 2 books (got %d)\n", langs.TotalBooks)
+	}
+
+	golang := langs.Children[0]
+	if golang.Path() != "Programming > Languages > Go" {
+		t.Fatalf("Path mismatch (got '%s')\n", golang.Path())
+	}
+	if ids := langs.SubtreeIds(); len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Fatalf("Subtree ids mismatch (got %v)\n", ids)
+	}
+	if !prog.IsAncestorOf(golang) || golang.IsAncestorOf(prog) {
+		t.Fatal("Programming should be ancestor of Go\n")
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags := utils.NormalizeTags(" Concurrency,  web   Services ,concurrency,, Reference")
+	if len(tags) != 3 || tags[0] != "concurrency" || tags[1] != "web services" || tags[2] != "reference" {
+		t.Fatalf("Tags mismatch (got %q)\n", tags)
+	}
+}